@@ -1,37 +1,51 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"os"
-	"strings"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/edgar-lins/controle-financeiro/internal/config"
 	"github.com/edgar-lins/controle-financeiro/internal/database"
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
 	"github.com/edgar-lins/controle-financeiro/internal/routes"
 )
 
-func corsMiddleware(next http.Handler) http.Handler {
+// Limites do servidor HTTP. WriteTimeout cobre as respostas mais lentas (relatórios e PDFs)
+// e precisa ficar abaixo do prazo em que o middleware de idempotência libera uma chave pendente.
+const (
+	readHeaderTimeout = 5 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+	maxHeaderBytes    = 1 << 20
+
+	// shutdownTimeout é quanto as requisições em andamento têm para terminar depois do
+	// SIGTERM (o Render espera 30s antes de matar o processo)
+	shutdownTimeout = 25 * time.Second
+)
+
+func corsMiddleware(allowedOrigins []string, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
 
-		// Obter origens permitidas do ambiente
-		allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
-		if allowedOrigins == "" {
-			// Fallback para localhost em desenvolvimento
-			allowedOrigins = "http://localhost:5173,http://localhost:3000,http://localhost:8080"
-		}
-
-		// Verificar se a origem está na lista de permitidas
-		origins := strings.Split(allowedOrigins, ",")
-		for _, allowed := range origins {
-			if strings.TrimSpace(allowed) == origin {
+		// Verificar se a origem está na lista de permitidas (config.AllowedOrigins)
+		for _, allowed := range allowedOrigins {
+			if allowed == origin {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				break
 			}
 		}
 
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, Idempotency-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Idempotent-Replayed")
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 
 		// Security Headers
@@ -51,29 +65,71 @@ func corsMiddleware(next http.Handler) http.Handler {
 }
 
 func main() {
-	// Obter configurações do ambiente
-	env := os.Getenv("ENVIRONMENT")
-	if env == "" {
-		env = "development"
-	}
+	// Logs em JSON no stdout, no nível de LOG_LEVEL depois que a configuração é lida
+	logLevel := new(slog.LevelVar)
+	slog.SetDefault(slog.New(middleware.NewLogHandler(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: logLevel}))))
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	// Configuração inválida (ou segredo ausente em produção) impede a inicialização
+	cfg, err := config.Load()
+	if err != nil {
+		slog.Error("Configuração inválida", "error", err)
+		os.Exit(1)
 	}
+	logLevel.Set(cfg.LogLevel)
 
-	fmt.Printf("🚀 Iniciando servidor em modo: %s\n", env)
+	slog.Info("Iniciando servidor", "environment", cfg.Environment)
 
-	db := database.Connect()
+	// Cancelado no SIGTERM (deploy) ou Ctrl+C; encerra também as tarefas em segundo plano
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := database.Connect(ctx, cfg.Database)
+	if err != nil {
+		slog.Error("Erro ao conectar no banco", "error", err)
+		os.Exit(1)
+	}
 	defer db.Close()
 
-	routes.SetupRoutes(db)
+	metrics.RegisterDB(db, "postgres")
+	mux := routes.SetupRoutes(ctx, cfg, db)
 
-	handler := corsMiddleware(http.DefaultServeMux)
+	auth := middleware.Auth{Secret: cfg.JWTSecret}
+	handler := corsMiddleware(cfg.AllowedOrigins, middleware.WithClientIP(cfg.TrustedProxyHops, middleware.WithRequestID(middleware.WithAccessLog(middleware.WithIdempotency(db, auth, cfg.IdempotencyRetention, middleware.WithMetrics(mux))))))
 
-	fmt.Printf("✅ Servidor rodando em http://localhost:%s\n", port)
-	err := http.ListenAndServe(":"+port, handler)
-	if err != nil {
-		panic("Erro ao iniciar servidor: " + err.Error())
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    maxHeaderBytes,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Servidor rodando", "port", cfg.Port)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			// os.Exit não roda os defers: fecha o banco antes de sair
+			slog.Error("Erro ao iniciar servidor", "error", err)
+			db.Close()
+			os.Exit(1)
+		}
+	case <-ctx.Done():
+	}
+
+	// Para de aceitar conexões e espera as requisições em andamento; o banco só é
+	// fechado (defer acima) depois que elas terminam
+	slog.Info("Encerrando servidor")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("Erro ao encerrar servidor", "error", err)
 	}
+	slog.Info("Servidor encerrado")
 }
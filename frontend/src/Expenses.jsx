@@ -14,6 +14,8 @@ import { PageHeader } from "./components/PageHeader";
 import { ConfirmModal } from "./components/ConfirmModal";
 import { Toast } from "./components/Toast";
 
+const PAGE_SIZE = 50;
+
 // Normalize date strings coming from API (with or without time)
 const parseDate = (value) => {
   if (!value) return null;
@@ -113,6 +115,7 @@ export default function Expenses() {
   const [filterMonth, setFilterMonth] = useState(defaultMonth);
   const [filterYear, setFilterYear] = useState(defaultYear);
   const [showAll, setShowAll] = useState(false);
+  const [nextCursor, setNextCursor] = useState(null);
 
   function startEdit(expense) {
     setEditingId(expense.id);
@@ -152,7 +155,8 @@ export default function Expenses() {
   }
 
   // 🔹 Buscar lista de gastos
-  async function fetchExpenses() {
+  // "Ver todos" é paginado por cursor; cursor carrega a próxima página
+  async function fetchExpenses(cursor = null) {
     try {
       const token = localStorage.getItem("token");
       const apiUrl = API_URL || "http://localhost:8080";
@@ -160,6 +164,9 @@ export default function Expenses() {
       if (!showAll) {
         if (filterMonth) params.set("month", String(filterMonth));
         if (filterYear) params.set("year", String(filterYear));
+      } else {
+        params.set("limit", String(PAGE_SIZE));
+        if (cursor) params.set("cursor", cursor);
       }
 
       const res = await fetch(`${apiUrl}/expenses${params.toString() ? `?${params.toString()}` : ""}`, {
@@ -167,10 +174,18 @@ export default function Expenses() {
       });
       if (!res.ok) throw new Error("Erro ao buscar gastos");
       const data = await res.json();
-      setExpenses(Array.isArray(data) ? data : []);
+      if (Array.isArray(data)) {
+        setExpenses(data);
+        setNextCursor(null);
+        return;
+      }
+      const items = Array.isArray(data?.items) ? data.items : [];
+      setExpenses((prev) => (cursor ? [...prev, ...items] : items));
+      setNextCursor(data?.next_cursor || null);
     } catch (error) {
       console.error("Erro ao buscar gastos:", error);
-      setExpenses([]);
+      if (!cursor) setExpenses([]);
+      setNextCursor(null);
     }
   }
 
@@ -521,6 +536,15 @@ export default function Expenses() {
                   </div>
                 </div>
               ))}
+              {nextCursor && (
+                <button
+                  type="button"
+                  onClick={() => fetchExpenses(nextCursor)}
+                  className="bg-slate-800 hover:bg-slate-700 text-gray-200 px-4 py-2 rounded-lg font-semibold transition"
+                >
+                  Carregar mais
+                </button>
+              )}
             </div>
           ) : (
             <div className="bg-white/5 border-2 border-dashed border-white/20 rounded-lg p-12 text-center">
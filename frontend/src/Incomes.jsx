@@ -13,6 +13,8 @@ import { ConfirmModal } from "./components/ConfirmModal";
 import { Toast } from "./components/Toast";
 import { PageHeader } from "./components/PageHeader";
 
+const PAGE_SIZE = 50;
+
 // Normalize dates from API (with or without time component)
 const parseDate = (value) => {
   if (!value) return null;
@@ -61,6 +63,7 @@ export default function Incomes() {
   const [filterMonth, setFilterMonth] = useState(defaultMonth);
   const [filterYear, setFilterYear] = useState(defaultYear);
   const [showAll, setShowAll] = useState(false);
+  const [nextCursor, setNextCursor] = useState(null);
 
   function startEdit(income) {
     setEditingId(income.id);
@@ -97,7 +100,8 @@ export default function Incomes() {
   }
 
   // 🔹 Buscar rendas
-  async function fetchIncomes() {
+  // "Ver todos" é paginado por cursor; cursor carrega a próxima página
+  async function fetchIncomes(cursor = null) {
     try {
       const token = localStorage.getItem("token");
       const apiUrl = API_URL || "http://localhost:8080";
@@ -105,6 +109,9 @@ export default function Incomes() {
       if (!showAll) {
         if (filterMonth) params.set("month", String(filterMonth));
         if (filterYear) params.set("year", String(filterYear));
+      } else {
+        params.set("limit", String(PAGE_SIZE));
+        if (cursor) params.set("cursor", cursor);
       }
 
       const res = await fetch(`${apiUrl}/incomes${params.toString() ? `?${params.toString()}` : ""}`, {
@@ -112,10 +119,18 @@ export default function Incomes() {
       });
       if (!res.ok) throw new Error("Erro ao buscar rendas");
       const data = await res.json();
-      setIncomes(Array.isArray(data) ? data : []);
+      if (Array.isArray(data)) {
+        setIncomes(data);
+        setNextCursor(null);
+        return;
+      }
+      const items = Array.isArray(data?.items) ? data.items : [];
+      setIncomes((prev) => (cursor ? [...prev, ...items] : items));
+      setNextCursor(data?.next_cursor || null);
     } catch (error) {
       console.error("Erro ao buscar rendas:", error);
-      setIncomes([]);
+      if (!cursor) setIncomes([]);
+      setNextCursor(null);
     }
   }
 
@@ -400,6 +415,15 @@ export default function Incomes() {
                   </div>
                 </div>
               ))}
+              {nextCursor && (
+                <button
+                  type="button"
+                  onClick={() => fetchIncomes(nextCursor)}
+                  className="bg-slate-800 hover:bg-slate-700 text-gray-200 px-4 py-2 rounded-lg font-semibold transition"
+                >
+                  Carregar mais
+                </button>
+              )}
             </div>
           ) : (
             <div className="bg-white/5 border-2 border-dashed border-white/20 rounded-lg p-12 text-center">
@@ -0,0 +1,189 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// devJWTSecret só é aceito fora de produção, quando JWT_SECRET não está definido
+const devJWTSecret = "dev-secret-change-me"
+
+// Config reúne as configurações do servidor, lidas das variáveis de ambiente uma vez
+// na inicialização
+type Config struct {
+	Environment string
+	Port        string
+	LogLevel    slog.Level
+
+	Database Database
+
+	JWTSecret string
+	// TokenTTL é a validade do token emitido no login
+	TokenTTL time.Duration
+
+	AllowedOrigins []string
+	// MetricsToken protege GET /metrics (Authorization: Bearer); vazio deixa a rota aberta
+	MetricsToken string
+	// TrustedProxyHops é quantos proxies reversos confiáveis (ex.: o do Render) ficam na
+	// frente do servidor; com zero o X-Forwarded-For é ignorado
+	TrustedProxyHops int
+
+	AttachmentsDir       string
+	IdempotencyRetention time.Duration
+	TrashRetention       time.Duration
+}
+
+// Database é a conexão com o PostgreSQL e o tamanho do pool
+type Database struct {
+	// DSN vem de DATABASE_URL ou, sem ela, de DB_HOST, DB_PORT, DB_USER, DB_PASSWORD e DB_NAME
+	DSN             string
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+}
+
+// IsProduction indica se ENVIRONMENT é production
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// Load lê e valida as configurações. Em desenvolvimento carrega antes .env.development
+// e .env (sem sobrescrever o ambiente). Retorna todos os problemas encontrados de uma vez.
+func Load() (*Config, error) {
+	env := os.Getenv("ENVIRONMENT")
+	if env == "" {
+		env = "development"
+	}
+	if env == "development" {
+		_ = godotenv.Load(".env.development")
+	}
+	_ = godotenv.Load() // fallback para .env
+
+	var errs []error
+	cfg := &Config{
+		Environment:    env,
+		Port:           stringVar("PORT", "8080"),
+		JWTSecret:      os.Getenv("JWT_SECRET"),
+		AllowedOrigins: listVar("ALLOWED_ORIGINS"),
+		MetricsToken:   os.Getenv("METRICS_TOKEN"),
+		AttachmentsDir: os.Getenv("ATTACHMENTS_DIR"),
+	}
+
+	if err := cfg.LogLevel.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		if os.Getenv("LOG_LEVEL") != "" {
+			errs = append(errs, fmt.Errorf("LOG_LEVEL inválido: %q (use debug, info, warn ou error)", os.Getenv("LOG_LEVEL")))
+		}
+		cfg.LogLevel = slog.LevelInfo
+	}
+
+	cfg.TrustedProxyHops = countVar(&errs, "TRUSTED_PROXY_HOPS", 0)
+	cfg.TokenTTL = durationVar(&errs, "JWT_TTL_HOURS", 24, time.Hour)
+	cfg.IdempotencyRetention = durationVar(&errs, "IDEMPOTENCY_RETENTION_HOURS", 24, time.Hour)
+	cfg.TrashRetention = durationVar(&errs, "TRASH_RETENTION_DAYS", 30, 24*time.Hour)
+
+	cfg.Database = Database{
+		DSN:             databaseDSN(),
+		MaxOpenConns:    intVar(&errs, "DB_MAX_OPEN_CONNS", 25),
+		MaxIdleConns:    intVar(&errs, "DB_MAX_IDLE_CONNS", 5),
+		ConnMaxLifetime: durationVar(&errs, "DB_CONN_MAX_LIFETIME_MINUTES", 30, time.Minute),
+	}
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		errs = append(errs, fmt.Errorf("DB_MAX_IDLE_CONNS (%d) maior que DB_MAX_OPEN_CONNS (%d)", cfg.Database.MaxIdleConns, cfg.Database.MaxOpenConns))
+	}
+
+	if cfg.IsProduction() {
+		// Em produção não há valores de desenvolvimento para segredos e origens
+		if cfg.JWTSecret == "" || cfg.JWTSecret == devJWTSecret {
+			errs = append(errs, errors.New("JWT_SECRET obrigatório em produção"))
+		}
+		if os.Getenv("DATABASE_URL") == "" && os.Getenv("DB_HOST") == "" {
+			errs = append(errs, errors.New("DATABASE_URL obrigatório em produção"))
+		}
+		if len(cfg.AllowedOrigins) == 0 {
+			errs = append(errs, errors.New("ALLOWED_ORIGINS obrigatório em produção"))
+		}
+		if cfg.MetricsToken == "" {
+			errs = append(errs, errors.New("METRICS_TOKEN obrigatório em produção"))
+		}
+	} else {
+		if cfg.JWTSecret == "" {
+			cfg.JWTSecret = devJWTSecret
+		}
+		if len(cfg.AllowedOrigins) == 0 {
+			cfg.AllowedOrigins = []string{"http://localhost:5173", "http://localhost:3000", "http://localhost:8080"}
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return cfg, nil
+}
+
+// databaseDSN usa DATABASE_URL (Render/produção) ou monta a conexão com as variáveis
+// individuais (desenvolvimento local)
+func databaseDSN() string {
+	if url := os.Getenv("DATABASE_URL"); url != "" {
+		return url
+	}
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
+		os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), os.Getenv("DB_USER"), os.Getenv("DB_PASSWORD"), os.Getenv("DB_NAME"))
+}
+
+func stringVar(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// listVar lê uma lista separada por vírgulas, ignorando itens vazios
+func listVar(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// intVar lê um inteiro positivo; ausente vale fallback, inválido entra em errs
+func intVar(errs *[]error, name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		*errs = append(*errs, fmt.Errorf("%s inválido: %q (esperado inteiro positivo)", name, value))
+		return fallback
+	}
+	return n
+}
+
+// countVar lê um inteiro não negativo; ausente vale fallback, inválido entra em errs
+func countVar(errs *[]error, name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		*errs = append(*errs, fmt.Errorf("%s inválido: %q (esperado inteiro não negativo)", name, value))
+		return fallback
+	}
+	return n
+}
+
+// durationVar lê um inteiro positivo na unidade informada (ex.: horas)
+func durationVar(errs *[]error, name string, fallback int, unit time.Duration) time.Duration {
+	return time.Duration(intVar(errs, name, fallback)) * unit
+}
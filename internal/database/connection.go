@@ -1,56 +1,72 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"os"
+	"log/slog"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/edgar-lins/controle-financeiro/internal/config"
 	_ "github.com/lib/pq"
 )
 
-func Connect() *sql.DB {
-	// Carregar variáveis de ambiente
-	env := os.Getenv("ENVIRONMENT")
-	if env == "" {
-		env = "development"
-	}
-
-	// Tenta carregar .env baseado no ambiente
-	if env == "development" {
-		_ = godotenv.Load(".env.development")
-	}
-	_ = godotenv.Load() // fallback para .env
-
-	// Verifica se existe DATABASE_URL (Render/produção)
-	databaseURL := os.Getenv("DATABASE_URL")
-
-	var psqlInfo string
-	if databaseURL != "" {
-		// Usar DATABASE_URL do Render
-		psqlInfo = databaseURL
-	} else {
-		// Usar variáveis individuais (desenvolvimento local)
-		host := os.Getenv("DB_HOST")
-		port := os.Getenv("DB_PORT")
-		user := os.Getenv("DB_USER")
-		password := os.Getenv("DB_PASSWORD")
-		dbname := os.Getenv("DB_NAME")
-
-		psqlInfo = fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-			host, port, user, password, dbname)
-	}
+const (
+	pingTimeout          = 5 * time.Second
+	initialReconnectWait = time.Second
+	maxReconnectWait     = 30 * time.Second
+)
 
-	db, err := sql.Open("postgres", psqlInfo)
+// Connect abre o pool de conexões com os limites de cfg. As novas tentativas em
+// segundo plano param quando ctx é cancelado (encerramento do servidor).
+func Connect(ctx context.Context, cfg config.Database) (*sql.DB, error) {
+	db, err := sql.Open("postgres", cfg.DSN)
 	if err != nil {
-		panic("Erro ao conectar no banco: " + err.Error())
+		return nil, fmt.Errorf("erro ao conectar no banco: %w", err)
 	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
 
-	err = db.Ping()
-	if err != nil {
-		panic("Banco inacessível: " + err.Error())
+	// Sem banco o servidor sobe mesmo assim, sem ficar pronto (/readyz responde 503),
+	// e continua tentando em segundo plano
+	if err := pingWithTimeout(ctx, db); err != nil {
+		slog.Warn("Banco inacessível, tentando reconectar", "error", err)
+		go reconnect(ctx, db)
+		return db, nil
 	}
 
-	fmt.Println("✅ Conexão com o banco de dados estabelecida com sucesso!")
-	return db
+	slog.Info("Conexão com o banco de dados estabelecida")
+	return db, nil
+}
+
+func pingWithTimeout(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+// reconnect tenta o ping com espera crescente (1s, 2s, 4s... até 30s) até o banco
+// responder ou ctx ser cancelado
+func reconnect(ctx context.Context, db *sql.DB) {
+	wait := initialReconnectWait
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+		err := pingWithTimeout(ctx, db)
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			slog.Info("Conexão com o banco de dados estabelecida")
+			return
+		}
+		if wait *= 2; wait > maxReconnectWait {
+			wait = maxReconnectWait
+		}
+		slog.Warn("Banco inacessível, tentando reconectar", "error", err, "retry_in", wait.String())
+	}
 }
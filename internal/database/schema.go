@@ -0,0 +1,27 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// SchemaVersion é a última migration que o código espera aplicada (migrations/034_*).
+// Ao criar uma migration, ela deve registrar seu número em schema_migrations e este
+// valor deve subir junto.
+const SchemaVersion = 34
+
+// AppliedSchemaVersion retorna a maior migration registrada em schema_migrations
+// (0 quando a tabela ainda não existe, isto é, antes da migration 032)
+func AppliedSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	var version int
+	err := db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&version)
+	return version, err
+}
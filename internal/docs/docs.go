@@ -0,0 +1,27 @@
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// Especificação OpenAPI da API (rotas /v1) e a página que a apresenta, embutidas no binário.
+// Ao criar ou alterar uma rota em routes.SetupRoutes, atualize openapi.json.
+
+//go:embed openapi.json
+var spec []byte
+
+//go:embed index.html
+var page []byte
+
+// Spec serve a especificação em /openapi.json
+func Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(spec)
+}
+
+// Page serve a documentação navegável em /docs, montada a partir de /openapi.json
+func Page(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(page)
+}
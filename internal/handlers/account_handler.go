@@ -1,13 +1,18 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
-	"strings"
+	"strconv"
+	"time"
 
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
 	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
 )
 
 type AccountHandler struct {
@@ -24,12 +29,53 @@ func (h *AccountHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := `INSERT INTO accounts (user_id, name, type, balance) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
-	err := h.DB.QueryRow(query, userID, acc.Name, acc.Type, acc.Balance).Scan(&acc.ID, &acc.CreatedAt)
+	if msg := validateStatementDays(&acc); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if acc.Currency != "" {
+		code, ok := normalizeCurrency(acc.Currency)
+		if !ok {
+			http.Error(w, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)", http.StatusBadRequest)
+			return
+		}
+		acc.Currency = code
+	}
+	if acc.PaymentAccountID != nil {
+		owns, err := h.ownsAccount(r.Context(), userID, *acc.PaymentAccountID)
+		if err != nil {
+			http.Error(w, "Erro ao validar conta de pagamento", http.StatusInternalServerError)
+			return
+		}
+		if !owns {
+			http.Error(w, "Conta de pagamento inválida", http.StatusForbidden)
+			return
+		}
+	}
+
+	// Sem moeda informada a conta usa a moeda base do usuário
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO accounts (user_id, name, type, balance, statement_closing_day, statement_due_day, payment_account_id, currency) VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE(NULLIF($8, ''), user_base_currency($1))) RETURNING id, created_at, currency`
+	err = tx.QueryRowContext(r.Context(), query, userID, acc.Name, acc.Type, acc.Balance, acc.StatementClosingDay, acc.StatementDueDay, acc.PaymentAccountID, acc.Currency).Scan(&acc.ID, &acc.CreatedAt, &acc.Currency)
 	if err != nil {
 		http.Error(w, "Erro ao criar conta", http.StatusInternalServerError)
 		return
 	}
+	if err := recordAudit(tx, r, userID, "account", acc.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
 
 	acc.UserID = userID
 	w.Header().Set("Content-Type", "application/json")
@@ -40,7 +86,22 @@ func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	rows, err := h.DB.Query(`SELECT id, name, type, balance, created_at FROM accounts WHERE user_id = $1 ORDER BY created_at DESC`, userID)
+	query := `SELECT id, name, type, balance, currency, created_at, statement_closing_day, statement_due_day, payment_account_id FROM accounts WHERE user_id = $1 AND deleted_at IS NULL`
+	args := []interface{}{userID}
+
+	// Em /v1/accounts/{id} retorna só a conta
+	single := r.PathValue("id")
+	if single != "" {
+		singleID, err := strconv.ParseInt(single, 10, 64)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		query += " AND id = $2"
+		args = append(args, singleID)
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), query+" ORDER BY created_at DESC", args...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar contas", http.StatusInternalServerError)
 		return
@@ -50,7 +111,7 @@ func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
 	accounts := []models.Account{}
 	for rows.Next() {
 		var acc models.Account
-		if err := rows.Scan(&acc.ID, &acc.Name, &acc.Type, &acc.Balance, &acc.CreatedAt); err != nil {
+		if err := rows.Scan(&acc.ID, &acc.Name, &acc.Type, &acc.Balance, &acc.Currency, &acc.CreatedAt, &acc.StatementClosingDay, &acc.StatementDueDay, &acc.PaymentAccountID); err != nil {
 			http.Error(w, "Erro ao ler contas", http.StatusInternalServerError)
 			return
 		}
@@ -59,13 +120,21 @@ func (h *AccountHandler) GetAccounts(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if single != "" {
+		if len(accounts) == 0 {
+			http.Error(w, "Conta não encontrada", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(accounts[0])
+		return
+	}
 	json.NewEncoder(w).Encode(accounts)
 }
 
 func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
@@ -78,32 +147,95 @@ func (h *AccountHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Simply update name, type, and balance with the values the user provided
-	query := `UPDATE accounts SET name = $1, type = $2, balance = $3 WHERE id = $4 AND user_id = $5`
-	result, err := h.DB.Exec(query, acc.Name, acc.Type, acc.Balance, id, userID)
+	if msg := validateStatementDays(&acc); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	if acc.Currency != "" {
+		code, ok := normalizeCurrency(acc.Currency)
+		if !ok {
+			http.Error(w, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)", http.StatusBadRequest)
+			return
+		}
+		acc.Currency = code
+	}
+	if acc.PaymentAccountID != nil {
+		owns, err := h.ownsAccount(r.Context(), userID, *acc.PaymentAccountID)
+		if err != nil {
+			http.Error(w, "Erro ao validar conta de pagamento", http.StatusInternalServerError)
+			return
+		}
+		if !owns {
+			http.Error(w, "Conta de pagamento inválida", http.StatusForbidden)
+			return
+		}
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if status, msg := checkPatchBase(r.Context(), tx, id, userID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "account", id)
 	if err != nil {
-		// log removido para produção
+		http.Error(w, "Erro ao buscar conta", http.StatusInternalServerError)
+		return
+	}
+
+	// Simply update name, type, balance and statement schedule with the values the user provided
+	// (currency is kept when not sent)
+	query := `UPDATE accounts SET name = $1, type = $2, balance = $3, statement_closing_day = $4, statement_due_day = $5, payment_account_id = $6, currency = COALESCE(NULLIF($7, ''), currency) WHERE id = $8 AND user_id = $9 AND deleted_at IS NULL`
+	result, err := tx.ExecContext(r.Context(), query, acc.Name, acc.Type, acc.Balance, acc.StatementClosingDay, acc.StatementDueDay, acc.PaymentAccountID, acc.Currency, id, userID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao atualizar conta", "error", err)
 		http.Error(w, "Erro ao atualizar conta", http.StatusInternalServerError)
 		return
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil || rowsAffected == 0 {
-		// log removido para produção
 		http.Error(w, "Conta não encontrada", http.StatusNotFound)
 		return
 	}
+	if err := recordAudit(tx, r, userID, "account", id, auditUpdate, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	// O saldo (e a moeda) informados valem para hoje, e a série é refeita para trás a
+	// partir deles: todo o histórico do patrimônio muda
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "Conta atualizada com sucesso"}`))
 }
 
+// PatchAccount altera só os campos enviados da conta (PATCH /v1/accounts/{id})
+func (h *AccountHandler) PatchAccount(w http.ResponseWriter, r *http.Request) {
+	mergePatch(w, r, h.DB, accountPatchSQL, "Conta não encontrada", h.UpdateAccount)
+}
+
 type transferRequest struct {
-	FromAccountID int64   `json:"from_account_id"`
-	ToAccountID   int64   `json:"to_account_id"`
-	Amount        float64 `json:"amount"`
-	Date          string  `json:"date"`
-	Description   string  `json:"description"`
+	FromAccountID int64    `json:"from_account_id"`
+	ToAccountID   int64    `json:"to_account_id"`
+	Amount        float64  `json:"amount"`
+	ToAmount      *float64 `json:"to_amount"` // valor creditado no destino quando as moedas diferem
+	Date          string   `json:"date"`
+	Description   string   `json:"description"`
+	Tags          []string `json:"tags"`
 }
 
 // TransferFunds moves money between two user accounts without affecting income/expense totals
@@ -113,108 +245,213 @@ func (h *AccountHandler) TransferFunds(w http.ResponseWriter, r *http.Request) {
 
 	var req transferRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		// log removido para produção
 		http.Error(w, "Dados inválidos", http.StatusBadRequest)
 		return
 	}
 
-	if req.FromAccountID == 0 || req.ToAccountID == 0 {
-		http.Error(w, "Contas de origem e destino são obrigatórias", http.StatusBadRequest)
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao iniciar transferência", "error", err)
+		http.Error(w, "Erro ao iniciar transferência", http.StatusInternalServerError)
 		return
 	}
+	defer tx.Rollback()
 
-	if req.FromAccountID == req.ToAccountID {
-		http.Error(w, "Escolha contas diferentes para transferir", http.StatusBadRequest)
+	if _, status, msg := h.createTransfer(tx, r, userID, req); status != 0 {
+		http.Error(w, msg, status)
 		return
 	}
 
-	if req.Amount <= 0 {
-		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+	if err := tx.Commit(); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao concluir transferência", "error", err)
+		http.Error(w, "Erro ao concluir transferência", http.StatusInternalServerError)
 		return
 	}
+	metrics.TransactionsCreated.WithLabelValues("transfer").Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message":"Transferência realizada com sucesso"}`))
+}
+
+// createTransfer validates and records the transfer inside tx, moving the balances
+// of both accounts (also used by the batch endpoint). Returns status 0 when ok.
+func (h *AccountHandler) createTransfer(tx *sql.Tx, r *http.Request, userID int, req transferRequest) (int64, int, string) {
+	if req.FromAccountID == 0 || req.ToAccountID == 0 {
+		return 0, http.StatusBadRequest, "Contas de origem e destino são obrigatórias"
+	}
+
+	if req.FromAccountID == req.ToAccountID {
+		return 0, http.StatusBadRequest, "Escolha contas diferentes para transferir"
+	}
+
+	if req.Amount <= 0 {
+		return 0, http.StatusBadRequest, "Valor deve ser maior que zero"
+	}
 
 	// Validate that both accounts belong to the user
 	var count int
-	if err := h.DB.QueryRow(`
-		SELECT COUNT(*) FROM accounts WHERE user_id = $1 AND id IN ($2, $3)
+	if err := tx.QueryRowContext(r.Context(), `
+		SELECT COUNT(*) FROM accounts WHERE user_id = $1 AND id IN ($2, $3) AND deleted_at IS NULL
 	`, userID, req.FromAccountID, req.ToAccountID).Scan(&count); err != nil {
-		// log removido para produção
-		http.Error(w, "Erro ao validar contas", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao validar contas", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao validar contas"
 	}
 	if count != 2 {
-		http.Error(w, "Contas inválidas", http.StatusForbidden)
-		return
+		return 0, http.StatusForbidden, "Contas inválidas"
 	}
 
 	// Get the current balance of the origin account
 	var currentBalance float64
-	if err := h.DB.QueryRow(`
-		SELECT balance FROM accounts WHERE user_id = $1 AND id = $2
+	if err := tx.QueryRowContext(r.Context(), `
+		SELECT balance FROM accounts WHERE user_id = $1 AND id = $2 AND deleted_at IS NULL
 	`, userID, req.FromAccountID).Scan(&currentBalance); err != nil {
-		// log removido para produção
-		http.Error(w, "Erro ao calcular saldo", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao calcular saldo", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao calcular saldo"
 	}
 
 	// Validate sufficient balance
 	if currentBalance < req.Amount {
-		http.Error(w, "Saldo insuficiente para esta transferência", http.StatusBadRequest)
-		return
+		return 0, http.StatusBadRequest, "Saldo insuficiente para esta transferência"
 	}
 
-	tx, err := h.DB.Begin()
+	// Between accounts in different currencies the destination is credited with
+	// to_amount, or with the amount converted at the transfer date rate
+	toAmount, status, msg := transferCreditAmount(r.Context(), tx, userID, req)
+	if status != 0 {
+		return 0, status, msg
+	}
+
+	var transferID int64
+	var transferDate time.Time
+	err := tx.QueryRowContext(r.Context(), `
+		INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, description, date, to_amount)
+		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6,'')::date, CURRENT_DATE), $7)
+		RETURNING id, date
+	`, userID, req.FromAccountID, req.ToAccountID, req.Amount, req.Description, req.Date, toAmount).Scan(&transferID, &transferDate)
 	if err != nil {
-		// log removido para produção
-		http.Error(w, "Erro ao iniciar transferência", http.StatusInternalServerError)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao registrar transferência", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao registrar transferência"
 	}
-	defer tx.Rollback()
 
-	dateValue := req.Date
-	if dateValue == "" {
-		// use today's date if not provided
-		dateValue = "" // handled by DEFAULT if empty
+	// Subtract from origin account and add to destination account
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`,
+		req.Amount, req.FromAccountID, userID)
+	if err != nil {
+		return 0, http.StatusInternalServerError, "Erro ao atualizar saldo da conta de origem"
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, description, date)
-		VALUES ($1, $2, $3, $4, $5, COALESCE(NULLIF($6,'')::date, CURRENT_DATE))
-	`, userID, req.FromAccountID, req.ToAccountID, req.Amount, req.Description, dateValue)
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`,
+		accountEffect(req.Amount, toAmount), req.ToAccountID, userID)
 	if err != nil {
-		// log removido para produção
-		http.Error(w, "Erro ao registrar transferência", http.StatusInternalServerError)
-		return
+		return 0, http.StatusInternalServerError, "Erro ao atualizar saldo da conta de destino"
 	}
 
-	if err := tx.Commit(); err != nil {
-		// log removido para produção
-		http.Error(w, "Erro ao concluir transferência", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err := staleNetWorthFrom(r.Context(), tx, userID, transferDate); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	// Update account balances after the transfer
-	// Subtract from origin account and add to destination account
-	_, err = h.DB.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`,
-		req.Amount, req.FromAccountID, userID)
+	// Transfers into a goal-backed account count as goal contributions
+	if err := recordTransferContributions(r.Context(), tx, userID, transferID, req.FromAccountID, req.ToAccountID, accountEffect(req.Amount, toAmount), transferDate); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar aporte da meta", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao registrar aporte da meta"
+	}
+
+	if err := setTransactionTags(r.Context(), tx, userID, "transfer", transferID, req.Tags); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao salvar tags", "error", err)
+		return 0, http.StatusInternalServerError, "Erro ao salvar tags"
+	}
+
+	if err := recordAudit(tx, r, userID, "transfer", transferID, auditCreate, nil); err != nil {
+		return 0, http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return transferID, 0, ""
+}
+
+// GetTransfers lists transfers, optionally filtered by account_id, month, year and tag
+func (h *AccountHandler) GetTransfers(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	baseQuery := `SELECT id, from_account_id, to_account_id, amount, to_amount, COALESCE(description, ''), date FROM transfers WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	// Em /v1/accounts/{id}/transfers a conta vem do caminho
+	accountParam := r.PathValue("id")
+	if accountParam == "" {
+		accountParam = r.URL.Query().Get("account_id")
+	}
+	if accountParam != "" {
+		accountID, err := strconv.ParseInt(accountParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Conta inválida", http.StatusBadRequest)
+			return
+		}
+		pos := strconv.Itoa(len(args) + 1)
+		baseQuery += " AND (from_account_id = $" + pos + " OR to_account_id = $" + pos + ")"
+		args = append(args, accountID)
+	}
+
+	if monthParam := r.URL.Query().Get("month"); monthParam != "" {
+		baseQuery += " AND EXTRACT(MONTH FROM date) = $" + strconv.Itoa(len(args)+1)
+		monthVal, _ := strconv.Atoi(monthParam)
+		args = append(args, monthVal)
+	}
+
+	if yearParam := r.URL.Query().Get("year"); yearParam != "" {
+		baseQuery += " AND EXTRACT(YEAR FROM date) = $" + strconv.Itoa(len(args)+1)
+		yearVal, _ := strconv.Atoi(yearParam)
+		args = append(args, yearVal)
+	}
+
+	if tags := parseTagFilter(r); tags != nil {
+		baseQuery += tagFilterClause("transfer", "id", len(args)+1)
+		args = append(args, pq.Array(tags))
+	}
+
+	baseQuery += " ORDER BY date DESC, id DESC"
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
 	if err != nil {
-		// log removido para produção
+		http.Error(w, "Erro ao buscar transferências", http.StatusInternalServerError)
+		return
 	}
+	defer rows.Close()
 
-	_, err = h.DB.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`,
-		req.Amount, req.ToAccountID, userID)
+	transfers := []models.Transfer{}
+	ids := []int64{}
+	for rows.Next() {
+		var t models.Transfer
+		if err := rows.Scan(&t.ID, &t.FromAccountID, &t.ToAccountID, &t.Amount, &t.ToAmount, &t.Description, &t.Date); err != nil {
+			http.Error(w, "Erro ao ler transferências", http.StatusInternalServerError)
+			return
+		}
+		transfers = append(transfers, t)
+		ids = append(ids, t.ID)
+	}
+
+	tags, err := loadTransactionTags(r.Context(), h.DB, userID, "transfer", ids)
 	if err != nil {
-		// log removido para produção
+		http.Error(w, "Erro ao buscar tags", http.StatusInternalServerError)
+		return
+	}
+	for i := range transfers {
+		transfers[i].Tags = tags[transfers[i].ID]
+		if transfers[i].Tags == nil {
+			transfers[i].Tags = []string{}
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message":"Transferência realizada com sucesso"}`))
+	json.NewEncoder(w).Encode(transfers)
 }
 
 func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
@@ -223,7 +460,7 @@ func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 
 	// Verifica se é a Carteira Geral
 	var accountName string
-	err := h.DB.QueryRow(`SELECT name FROM accounts WHERE id = $1 AND user_id = $2`, id, userID).Scan(&accountName)
+	err := h.DB.QueryRowContext(r.Context(), `SELECT name FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID).Scan(&accountName)
 	if err != nil {
 		http.Error(w, "Conta não encontrada", http.StatusNotFound)
 		return
@@ -234,38 +471,134 @@ func (h *AccountHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.DB.Exec(`DELETE FROM accounts WHERE id = $1 AND user_id = $2`, id, userID)
+	// Posições em investimentos impedem a exclusão definitiva da conta
+	var holdings int
+	if err := h.DB.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM holdings WHERE account_id = $1 AND user_id = $2`, id, userID).Scan(&holdings); err != nil {
+		http.Error(w, "Erro ao buscar conta", http.StatusInternalServerError)
+		return
+	}
+	if holdings > 0 {
+		http.Error(w, "Não é possível deletar essa conta. Verifique se há investimentos vinculados.", http.StatusConflict)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "account", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar conta", http.StatusInternalServerError)
+		return
+	}
+
+	// Vai para a lixeira: o saldo sai do patrimônio e as transações continuam vinculadas
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET deleted_at = NOW() WHERE id = $1 AND user_id = $2`, id, userID)
 	if err != nil {
-		// log removido para produção
-		// Só retorna 409 se for erro de constraint
-		if strings.Contains(err.Error(), "violates foreign key constraint") || strings.Contains(err.Error(), "constraint failed") {
-			http.Error(w, "Não é possível deletar essa conta. Verifique se há transações vinculadas.", http.StatusConflict)
-			return
-		}
 		http.Error(w, "Erro ao deletar conta", http.StatusInternalServerError)
 		return
 	}
+	if err := recordAudit(tx, r, userID, "account", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// transferCreditAmount retorna o valor a creditar na conta de destino quando as
+// contas têm moedas diferentes (nil quando é a mesma moeda). Retorna status 0 quando ok.
+func transferCreditAmount(ctx context.Context, q rowQueryer, userID int, req transferRequest) (*float64, int, string) {
+	var fromCurrency, toCurrency string
+	err := q.QueryRowContext(ctx, `
+		SELECT (SELECT currency FROM accounts WHERE id = $2 AND user_id = $1),
+			(SELECT currency FROM accounts WHERE id = $3 AND user_id = $1)
+	`, userID, req.FromAccountID, req.ToAccountID).Scan(&fromCurrency, &toCurrency)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao buscar moeda das contas"
+	}
+	if fromCurrency == toCurrency {
+		return nil, 0, ""
+	}
+
+	if req.ToAmount != nil {
+		if *req.ToAmount <= 0 {
+			return nil, http.StatusBadRequest, "Valor creditado no destino deve ser positivo"
+		}
+		v := roundMoney(*req.ToAmount)
+		return &v, 0, ""
+	}
+
+	date := time.Now().UTC()
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return nil, http.StatusBadRequest, "Data inválida, use YYYY-MM-DD"
+		}
+		date = parsed
+	}
+	var converted sql.NullFloat64
+	err = q.QueryRowContext(ctx, `SELECT convert_amount($1, $2, $3, $4, $5)`, userID, req.Amount, fromCurrency, toCurrency, date).Scan(&converted)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao converter moeda"
+	}
+	if !converted.Valid {
+		return nil, http.StatusBadRequest, "Sem cotação de " + fromCurrency + " para " + toCurrency + "; cadastre em /exchange-rates ou informe to_amount"
+	}
+	v := converted.Float64
+	return &v, 0, ""
+}
+
+// validateStatementDays checks the card statement schedule; only cartao accounts keep it
+func validateStatementDays(acc *models.Account) string {
+	if acc.Type != "cartao" {
+		acc.StatementClosingDay = nil
+		acc.StatementDueDay = nil
+		acc.PaymentAccountID = nil
+		return ""
+	}
+	for _, day := range []*int{acc.StatementClosingDay, acc.StatementDueDay} {
+		if day != nil && (*day < 1 || *day > 31) {
+			return "Dia de fechamento/vencimento deve estar entre 1 e 31"
+		}
+	}
+	return ""
+}
+
+func (h *AccountHandler) ownsAccount(ctx context.Context, userID int, accountID int64) (bool, error) {
+	var count int
+	err := h.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, accountID, userID).Scan(&count)
+	return count > 0, err
+}
+
 // GetOrCreateDefaultAccount busca ou cria uma "Carteira Geral" padrão para o usuário
-func (h *AccountHandler) GetOrCreateDefaultAccount(userID int) (int64, error) {
+func (h *AccountHandler) GetOrCreateDefaultAccount(ctx context.Context, userID int) (int64, error) {
 	var accountID int64
 
 	// Primeiro, tenta buscar uma carteira geral existente
-	err := h.DB.QueryRow(`
+	err := h.DB.QueryRowContext(ctx, `
 		SELECT id FROM accounts 
-		WHERE user_id = $1 AND name = 'Carteira Geral'
+		WHERE user_id = $1 AND name = 'Carteira Geral' AND deleted_at IS NULL
 		ORDER BY created_at ASC
 		LIMIT 1
 	`, userID).Scan(&accountID)
 
 	if err == sql.ErrNoRows {
-		// Se não existe, cria uma nova
-		err = h.DB.QueryRow(`
-			INSERT INTO accounts (user_id, name, type, balance)
-			VALUES ($1, 'Carteira Geral', 'corrente', 0)
+		// Se não existe, cria uma nova, na moeda base do usuário
+		err = h.DB.QueryRowContext(ctx, `
+			INSERT INTO accounts (user_id, name, type, balance, currency)
+			VALUES ($1, 'Carteira Geral', 'corrente', 0, user_base_currency($1))
 			RETURNING id
 		`, userID).Scan(&accountID)
 
@@ -0,0 +1,422 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/edgar-lins/controle-financeiro/internal/storage"
+)
+
+type AttachmentHandler struct {
+	DB      *sql.DB
+	Storage storage.Storage
+	// MaxSize e Quota em bytes; zero usa os padrões abaixo
+	MaxSize int64
+	Quota   int64
+}
+
+const (
+	defaultAttachmentMaxSize = 10 << 20  // 10 MB por arquivo
+	defaultAttachmentQuota   = 100 << 20 // 100 MB por usuário
+)
+
+// attachmentEntities mapeia o tipo aceito no parâmetro type para a tabela dona do anexo
+var attachmentEntities = map[string]string{
+	"expense": "expenses",
+	"income":  "incomes",
+	"goal":    "goals",
+}
+
+// allowedAttachmentTypes são os tipos aceitos, detectados pelo conteúdo do arquivo
+// (e não pela extensão ou pelo Content-Type enviado pelo cliente)
+var allowedAttachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+func (h *AttachmentHandler) maxSize() int64 {
+	if h.MaxSize > 0 {
+		return h.MaxSize
+	}
+	return defaultAttachmentMaxSize
+}
+
+func (h *AttachmentHandler) quota() int64 {
+	if h.Quota > 0 {
+		return h.Quota
+	}
+	return defaultAttachmentQuota
+}
+
+// attachmentTarget lê type e id da query e confere se o registro pertence ao usuário.
+// Retorna status 0 quando está tudo certo.
+func (h *AttachmentHandler) attachmentTarget(r *http.Request, userID int) (string, int64, int, string) {
+	entityType := r.URL.Query().Get("type")
+	table, ok := attachmentEntities[entityType]
+	if !ok {
+		return "", 0, http.StatusBadRequest, "Tipo inválido, use expense, income ou goal"
+	}
+	entityID, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		return "", 0, http.StatusBadRequest, "ID é obrigatório"
+	}
+
+	var count int
+	if err := h.DB.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM `+table+` WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, entityID, userID).Scan(&count); err != nil {
+		return "", 0, http.StatusInternalServerError, "Erro ao buscar registro"
+	}
+	if count == 0 {
+		return "", 0, http.StatusNotFound, "Registro não encontrado"
+	}
+	return entityType, entityID, 0, ""
+}
+
+func (h *AttachmentHandler) usedSpace(ctx context.Context, userID int) (int64, error) {
+	var used int64
+	err := h.DB.QueryRowContext(ctx, `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = $1`, userID).Scan(&used)
+	return used, err
+}
+
+func newStorageKey(userID int, ext string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return strconv.Itoa(userID) + "/" + hex.EncodeToString(b) + ext, nil
+}
+
+// GetAttachments lista os anexos de um gasto, renda ou meta (?type=expense&id=1)
+func (h *AttachmentHandler) GetAttachments(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	entityType, entityID, status, msg := h.attachmentTarget(r, userID)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, entity_type, entity_id, filename, content_type, size, created_at
+		FROM attachments
+		WHERE user_id = $1 AND entity_type = $2 AND entity_id = $3
+		ORDER BY created_at, id
+	`, userID, entityType, entityID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar anexos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar anexos", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	attachments := []models.Attachment{}
+	for rows.Next() {
+		var a models.Attachment
+		if err := rows.Scan(&a.ID, &a.EntityType, &a.EntityID, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler anexos", http.StatusInternalServerError)
+			return
+		}
+		attachments = append(attachments, a)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attachments)
+}
+
+// UploadAttachment recebe um arquivo (multipart, campo "file") e o liga ao registro
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	entityType, entityID, status, msg := h.attachmentTarget(r, userID)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	maxSize := h.maxSize()
+	// Folga de 1 MB para os cabeçalhos do multipart
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+(1<<20))
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "Arquivo maior que o limite de "+strconv.FormatInt(maxSize>>20, 10)+" MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Envie o arquivo no campo file (multipart/form-data)", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Envie o arquivo no campo file (multipart/form-data)", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size == 0 {
+		http.Error(w, "Arquivo vazio", http.StatusBadRequest)
+		return
+	}
+	if header.Size > maxSize {
+		http.Error(w, "Arquivo maior que o limite de "+strconv.FormatInt(maxSize>>20, 10)+" MB", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		http.Error(w, "Erro ao ler arquivo", http.StatusBadRequest)
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		http.Error(w, "Tipo de arquivo não permitido, envie JPEG, PNG, WebP ou PDF", http.StatusUnsupportedMediaType)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		http.Error(w, "Erro ao ler arquivo", http.StatusInternalServerError)
+		return
+	}
+
+	used, err := h.usedSpace(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Erro ao verificar cota de anexos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao verificar cota de anexos", "error", err)
+		return
+	}
+	if used+header.Size > h.quota() {
+		http.Error(w, "Cota de anexos excedida", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	key, err := newStorageKey(userID, ext)
+	if err != nil {
+		http.Error(w, "Erro ao salvar anexo", http.StatusInternalServerError)
+		return
+	}
+	size, err := h.Storage.Save(key, file)
+	if err != nil {
+		http.Error(w, "Erro ao salvar anexo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao salvar anexo", "error", err)
+		return
+	}
+
+	filename := filepath.Base(strings.ReplaceAll(header.Filename, `\`, "/"))
+	if filename == "." || filename == "/" {
+		filename = "anexo" + ext
+	}
+	if len([]rune(filename)) > 255 {
+		filename = string([]rune(filename)[:255])
+	}
+
+	attachment := models.Attachment{
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Filename:    filename,
+		ContentType: contentType,
+		Size:        size,
+	}
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		h.Storage.Delete(key)
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO attachments (user_id, entity_type, entity_id, filename, content_type, size, storage_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at
+	`, userID, entityType, entityID, filename, contentType, size, key).Scan(&attachment.ID, &attachment.CreatedAt)
+	if err != nil {
+		h.Storage.Delete(key)
+		http.Error(w, "Erro ao salvar anexo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao salvar anexo", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "attachment", attachment.ID, auditCreate, nil); err != nil {
+		h.Storage.Delete(key)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		h.Storage.Delete(key)
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+// DownloadAttachment devolve o conteúdo do anexo (?id=1)
+func (h *AttachmentHandler) DownloadAttachment(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var filename, contentType, key string
+	var size int64
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT filename, content_type, size, storage_key FROM attachments WHERE id = $1 AND user_id = $2
+	`, id, userID).Scan(&filename, &contentType, &size, &key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anexo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar anexo", http.StatusInternalServerError)
+		return
+	}
+
+	f, err := h.Storage.Open(key)
+	if errors.Is(err, storage.ErrNotFound) {
+		http.Error(w, "Arquivo do anexo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao abrir anexo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao abrir anexo", "error", err)
+		return
+	}
+	defer f.Close()
+
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = "attachment"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filename}))
+	io.Copy(w, f)
+}
+
+// DeleteAttachment remove o anexo e o arquivo armazenado (?id=1)
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "attachment", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar anexo", http.StatusInternalServerError)
+		return
+	}
+
+	var key string
+	err = tx.QueryRowContext(r.Context(), `DELETE FROM attachments WHERE id = $1 AND user_id = $2 RETURNING storage_key`, id, userID).Scan(&key)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Anexo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao deletar anexo", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "attachment", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	removeStoredFiles(h.Storage, []string{key})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAttachmentUsage retorna o espaço usado pelos anexos e a cota do usuário
+func (h *AttachmentHandler) GetAttachmentUsage(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	used, err := h.usedSpace(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Erro ao verificar cota de anexos", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(models.AttachmentUsage{Used: used, Quota: h.quota()})
+}
+
+// queryer é satisfeito por *sql.DB e *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// deleteEntityAttachments apaga os anexos de um registro removido e retorna as
+// chaves dos arquivos, que devem ser removidas do armazenamento após o commit
+func deleteEntityAttachments(ctx context.Context, q queryer, userID int, entityType string, entityID interface{}) ([]string, error) {
+	rows, err := q.QueryContext(ctx, `
+		DELETE FROM attachments WHERE user_id = $1 AND entity_type = $2 AND entity_id = $3
+		RETURNING storage_key
+	`, userID, entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// removeStoredFiles apaga os arquivos do armazenamento; falhas só são registradas,
+// pois os metadados já foram removidos
+func removeStoredFiles(s storage.Storage, keys []string) {
+	if s == nil {
+		return
+	}
+	for _, key := range keys {
+		if err := s.Delete(key); err != nil {
+			slog.Error("Erro ao remover arquivo de anexo", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+)
+
+const (
+	auditCreate = "create"
+	auditUpdate = "update"
+	auditDelete = "delete"
+)
+
+// auditTables mapeia o tipo de entidade auditada para a sua tabela
+var auditTables = map[string]string{
+	"expense":              "expenses",
+	"income":               "incomes",
+	"account":              "accounts",
+	"transfer":             "transfers",
+	"goal":                 "goals",
+	"goal_contribution":    "goal_contributions",
+	"tag":                  "tags",
+	"attachment":           "attachments",
+	"preferences":          "user_preferences",
+	"exchange_rate":        "exchange_rates",
+	"holding":              "holdings",
+	"investment_operation": "investment_operations",
+	"asset_quote":          "asset_quotes",
+	"loan":                 "loans",
+	"loan_payment":         "loan_payments",
+}
+
+// auditOwner é o filtro de dono ($2) das tabelas sem coluna user_id
+var auditOwner = map[string]string{
+	"loan_payment": "t.loan_id IN (SELECT id FROM loans WHERE user_id = $2)",
+}
+
+// auditExecer é satisfeito por *sql.DB e *sql.Tx
+type auditExecer interface {
+	rowQueryer
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// auditSnapshot lê o registro do usuário como JSON para o log (nil quando não existe)
+func auditSnapshot(ctx context.Context, q rowQueryer, userID int, entityType string, id interface{}) (json.RawMessage, error) {
+	owner, ok := auditOwner[entityType]
+	if !ok {
+		owner = "t.user_id = $2"
+	}
+	var data []byte
+	err := q.QueryRowContext(ctx, `SELECT row_to_json(t) FROM `+auditTables[entityType]+` t WHERE t.id = $1 AND `+owner, id, userID).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// auditRow é o estado de uma linha lida por auditSnapshots
+type auditRow struct {
+	ID   int64
+	Data json.RawMessage
+}
+
+// auditSnapshots lê o estado das linhas do usuário que passam no filtro where, para
+// alterações em lote. O usuário é o parâmetro $1 do filtro.
+func auditSnapshots(ctx context.Context, q queryer, userID int, entityType, where string, args ...interface{}) ([]auditRow, error) {
+	rows, err := q.QueryContext(ctx, `SELECT t.id, row_to_json(t) FROM `+auditTables[entityType]+` t WHERE t.user_id = $1 AND `+where+` ORDER BY t.id`,
+		append([]interface{}{userID}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	snapshots := []auditRow{}
+	for rows.Next() {
+		var row auditRow
+		if err := rows.Scan(&row.ID, &row.Data); err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, row)
+	}
+	return snapshots, rows.Err()
+}
+
+// recordAudit grava uma entrada no log de auditoria. O estado posterior é lido do
+// banco (exceto em exclusões), então deve ser chamado depois da alteração e, se
+// houver transação, dentro dela. Sem estado anterior nem posterior (registro de
+// outro usuário ou inexistente) nada é gravado.
+func recordAudit(q auditExecer, r *http.Request, userID int, entityType string, entityID interface{}, action string, before json.RawMessage) error {
+	var after json.RawMessage
+	if action != auditDelete {
+		var err error
+		if after, err = auditSnapshot(r.Context(), q, userID, entityType, entityID); err != nil {
+			return err
+		}
+	}
+	if before == nil && after == nil {
+		return nil
+	}
+	_, err := q.ExecContext(r.Context(), `
+		INSERT INTO audit_log (user_id, entity_type, entity_id, action, before, after, request_id, ip)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''))
+	`, userID, entityType, entityID, action, auditJSON(before), auditJSON(after), middleware.RequestID(r), middleware.ClientIP(r))
+	return err
+}
+
+// upsertAction é a ação auditada de um upsert: criação quando não havia registro
+func upsertAction(before json.RawMessage) string {
+	if before == nil {
+		return auditCreate
+	}
+	return auditUpdate
+}
+
+// auditJSON converte o snapshot em parâmetro JSONB (NULL quando vazio)
+func auditJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type AuditHandler struct {
+	DB *sql.DB
+}
+
+// GetAuditLog lista o histórico de alterações do usuário, do mais recente para o
+// mais antigo. Parâmetros opcionais: entity_type e entity_id (histórico de um
+// registro), action, from, to (YYYY-MM-DD), limit e cursor (next_cursor da página anterior)
+func (h *AuditHandler) GetAuditLog(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	query := r.URL.Query()
+
+	baseQuery := `SELECT id, entity_type, entity_id, action, before, after, request_id, ip, created_at FROM audit_log WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if v := query.Get("entity_type"); v != "" {
+		if _, ok := auditTables[v]; !ok {
+			http.Error(w, "Tipo de entidade inválido", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND entity_type = $" + strconv.Itoa(len(args)+1)
+		args = append(args, v)
+	}
+	if v := query.Get("entity_id"); v != "" {
+		entityID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, "entity_id inválido", http.StatusBadRequest)
+			return
+		}
+		if query.Get("entity_type") == "" {
+			http.Error(w, "entity_id exige entity_type", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND entity_id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, entityID)
+	}
+	if v := query.Get("action"); v != "" {
+		switch v {
+		case auditCreate, auditUpdate, auditDelete:
+		default:
+			http.Error(w, "Ação inválida, use create, update ou delete", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND action = $" + strconv.Itoa(len(args)+1)
+		args = append(args, v)
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<"} {
+		if v := query.Get(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			if param == "to" {
+				date = date.AddDate(0, 0, 1)
+			}
+			baseQuery += " AND created_at " + op + " $" + strconv.Itoa(len(args)+1)
+			args = append(args, date)
+		}
+	}
+
+	limit := defaultPageLimit
+	if v := query.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxPageLimit {
+			http.Error(w, "Parâmetro limit deve estar entre 1 e "+strconv.Itoa(maxPageLimit), http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+	// O id cresce com o tempo, então serve de cursor
+	if v := query.Get("cursor"); v != "" {
+		afterID, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			http.Error(w, errInvalidCursor.Error(), http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND id < $" + strconv.Itoa(len(args)+1)
+		args = append(args, afterID)
+	}
+	baseQuery += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args)+1)
+	args = append(args, limit+1)
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar auditoria", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		var before, after []byte
+		if err := rows.Scan(&entry.ID, &entry.EntityType, &entry.EntityID, &entry.Action, &before, &after, &entry.RequestID, &entry.IP, &entry.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler auditoria", http.StatusInternalServerError)
+			return
+		}
+		entry.Before = before
+		entry.After = after
+		entries = append(entries, entry)
+	}
+
+	// Uma linha além do limite indica que existe próxima página
+	var nextCursor *string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		c := strconv.FormatInt(entries[len(entries)-1].ID, 10)
+		nextCursor = &c
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":       entries,
+		"next_cursor": nextCursor,
+		"limit":       limit,
+	})
+}
@@ -4,16 +4,19 @@ import (
 	"database/sql"
 	"encoding/json"
 	"net/http"
-	"os"
 	"sync"
 	"time"
 
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type AuthHandler struct {
-	DB *sql.DB
+	DB        *sql.DB
+	JWTSecret string
+	TokenTTL  time.Duration
 }
 
 type SignupRequest struct {
@@ -89,11 +92,12 @@ func (h *AuthHandler) Signup(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Erro ao criar usuário", http.StatusInternalServerError)
 		return
 	}
-	_, err = h.DB.Exec(`INSERT INTO users(email, password_hash, first_name, last_name) VALUES($1, $2, $3, $4)`, req.Email, string(hash), req.FirstName, req.LastName)
+	_, err = h.DB.ExecContext(r.Context(), `INSERT INTO users(email, password_hash, first_name, last_name) VALUES($1, $2, $3, $4)`, req.Email, string(hash), req.FirstName, req.LastName)
 	if err != nil {
 		http.Error(w, "Erro ao salvar usuário", http.StatusConflict)
 		return
 	}
+	metrics.Signups.Inc()
 	w.WriteHeader(http.StatusCreated)
 }
 
@@ -104,8 +108,9 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Rate limiting: máx 5 tentativas por IP em 15 minutos
-	clientIP := r.RemoteAddr
+	clientIP := middleware.ClientIP(r)
 	if !loginLimiter.IsAllowed(clientIP, 5, 15) {
+		metrics.LoginAttempts.WithLabelValues("rate_limited").Inc()
 		w.Header().Set("Retry-After", "900") // 15 minutos em segundos
 		http.Error(w, "Muitas tentativas de login. Tente novamente em 15 minutos.", http.StatusTooManyRequests)
 		return
@@ -118,29 +123,28 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 	var id int
 	var hash, firstName, lastName string
-	err := h.DB.QueryRow(`SELECT id, password_hash, first_name, last_name FROM users WHERE email = $1`, req.Email).Scan(&id, &hash, &firstName, &lastName)
+	err := h.DB.QueryRowContext(r.Context(), `SELECT id, password_hash, first_name, last_name FROM users WHERE email = $1`, req.Email).Scan(&id, &hash, &firstName, &lastName)
 	if err != nil {
+		metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
 		http.Error(w, "Credenciais inválidas", http.StatusUnauthorized)
 		return
 	}
 	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		metrics.LoginAttempts.WithLabelValues("invalid_credentials").Inc()
 		http.Error(w, "Credenciais inválidas", http.StatusUnauthorized)
 		return
 	}
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		secret = "dev-secret-change-me"
-	}
 	claims := jwt.MapClaims{
 		"sub": id,
-		"exp": time.Now().Add(24 * time.Hour).Unix(),
+		"exp": time.Now().Add(h.TokenTTL).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signed, err := token.SignedString([]byte(secret))
+	signed, err := token.SignedString([]byte(h.JWTSecret))
 	if err != nil {
 		http.Error(w, "Erro ao gerar token", http.StatusInternalServerError)
 		return
 	}
+	metrics.LoginAttempts.WithLabelValues("success").Inc()
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(TokenResponse{
 		Token:     signed,
@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+const (
+	// Um padrão é recorrente quando aparece em pelo menos 3 meses distintos
+	// da janela de histórico e a última ocorrência é recente
+	recurringMinMonths   = 3
+	recurringHistoryMons = 6
+	recurringMaxGapDays  = 45
+)
+
+// forecastAccount é o estado inicial de uma conta na simulação
+type forecastAccount struct {
+	ID               int64
+	Name             string
+	Type             string
+	Balance          float64 // saldo no fim do dia de hoje
+	ClosingDay       *int
+	DueDay           *int
+	PaymentAccountID *int64
+	InitialOwed      float64 // fatura já fechada e ainda não vencida
+}
+
+// forecastTransaction é uma renda ou gasto usado na detecção de recorrência
+type forecastTransaction struct {
+	Kind        string // income, expense
+	Description string
+	AccountID   int64
+	Amount      float64
+	Date        time.Time
+}
+
+// forecastGoalSchedule é um aporte mensal programado em meta
+type forecastGoalSchedule struct {
+	Name      string
+	Remaining float64
+	Amount    float64
+	Day       int
+	FromID    int64
+	ToID      *int64 // conta da meta, quando lastreada
+}
+
+// dateInMonth retorna o dia informado no mês, limitado ao último dia do mês
+func dateInMonth(year int, month time.Month, day int) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// previousOccurrence retorna a data mais recente <= ref que cai no dia mensal informado
+func previousOccurrence(day int, ref time.Time) time.Time {
+	d := dateInMonth(ref.Year(), ref.Month(), day)
+	if d.After(ref) {
+		d = dateInMonth(ref.Year(), ref.Month()-1, day)
+	}
+	return d
+}
+
+// nextOccurrence retorna a primeira data > after que cai no dia mensal informado
+func nextOccurrence(day int, after time.Time) time.Time {
+	d := dateInMonth(after.Year(), after.Month(), day)
+	if !d.After(after) {
+		d = dateInMonth(after.Year(), after.Month()+1, day)
+	}
+	return d
+}
+
+func recurringKey(t forecastTransaction) string {
+	return t.Kind + "|" + strings.ToLower(strings.TrimSpace(t.Description)) + "|" + strconv.FormatInt(t.AccountID, 10)
+}
+
+func monthKey(d time.Time) string {
+	return d.Format("2006-01")
+}
+
+// detectRecurring encontra rendas e gastos que se repetem mensalmente e projeta
+// as próximas ocorrências entre amanhã e end. Meses que já têm um lançamento do
+// mesmo padrão (inclusive lançamentos futuros conhecidos) não são projetados de novo.
+func detectRecurring(txs []forecastTransaction, today, end time.Time) ([]models.RecurringPattern, []models.ForecastEvent) {
+	historyStart := time.Date(today.Year(), today.Month()-recurringHistoryMons, 1, 0, 0, 0, 0, time.UTC)
+
+	groups := make(map[string][]forecastTransaction)
+	seenMonths := make(map[string]map[string]bool)
+	var keys []string
+	for _, t := range txs {
+		key := recurringKey(t)
+		if seenMonths[key] == nil {
+			seenMonths[key] = make(map[string]bool)
+		}
+		seenMonths[key][monthKey(t.Date)] = true
+
+		if t.Date.Before(historyStart) || t.Date.After(today) {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], t)
+	}
+	sort.Strings(keys)
+
+	patterns := []models.RecurringPattern{}
+	events := []models.ForecastEvent{}
+	for _, key := range keys {
+		history := groups[key]
+		months := make(map[string]bool)
+		for _, t := range history {
+			months[monthKey(t.Date)] = true
+		}
+		if len(months) < recurringMinMonths {
+			continue
+		}
+
+		sort.Slice(history, func(i, j int) bool { return history[i].Date.Before(history[j].Date) })
+		last := history[len(history)-1]
+		if today.Sub(last.Date).Hours()/24 > recurringMaxGapDays {
+			continue
+		}
+
+		// Valor médio das últimas 3 ocorrências e dia mediano do mês
+		recent := history
+		if len(recent) > 3 {
+			recent = recent[len(recent)-3:]
+		}
+		var sum float64
+		for _, t := range recent {
+			sum += t.Amount
+		}
+		amount := roundMoney(sum / float64(len(recent)))
+
+		days := make([]int, 0, len(history))
+		for _, t := range history {
+			days = append(days, t.Date.Day())
+		}
+		sort.Ints(days)
+		day := days[len(days)/2]
+
+		patterns = append(patterns, models.RecurringPattern{
+			Kind:        last.Kind,
+			Description: last.Description,
+			AccountID:   last.AccountID,
+			Amount:      amount,
+			Day:         day,
+			Occurrences: len(history),
+		})
+
+		signed := amount
+		if last.Kind == "expense" {
+			signed = -amount
+		}
+		for m := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC); !m.After(end); m = m.AddDate(0, 1, 0) {
+			date := dateInMonth(m.Year(), m.Month(), day)
+			if !date.After(today) || date.After(end) || seenMonths[key][monthKey(date)] {
+				continue
+			}
+			events = append(events, models.ForecastEvent{
+				Date:        date,
+				AccountID:   last.AccountID,
+				Amount:      signed,
+				Kind:        "recurring_" + last.Kind,
+				Description: last.Description,
+			})
+		}
+	}
+
+	return patterns, events
+}
+
+// scheduleGoalContributions projeta os aportes programados até a meta ser atingida
+func scheduleGoalContributions(goals []forecastGoalSchedule, today, end time.Time) []models.ForecastEvent {
+	events := []models.ForecastEvent{}
+	for _, g := range goals {
+		if g.ToID != nil && *g.ToID == g.FromID {
+			continue
+		}
+		remaining := g.Remaining
+		for date := nextOccurrence(g.Day, today); !date.After(end) && remaining > 0; date = nextOccurrence(g.Day, date) {
+			amount := g.Amount
+			if amount > remaining {
+				amount = roundMoney(remaining)
+			}
+			remaining -= amount
+
+			description := "Aporte na meta " + g.Name
+			events = append(events, models.ForecastEvent{
+				Date: date, AccountID: g.FromID, Amount: -amount, Kind: "goal_contribution", Description: description,
+			})
+			if g.ToID != nil {
+				events = append(events, models.ForecastEvent{
+					Date: date, AccountID: *g.ToID, Amount: amount, Kind: "goal_contribution", Description: description,
+				})
+			}
+		}
+	}
+	return events
+}
+
+// scheduleLoanInstallments projeta as parcelas em aberto dos empréstimos com conta
+// de pagamento que vencem entre amanhã e end
+func scheduleLoanInstallments(loans []models.Loan, payments map[int64][]models.LoanPayment, today, end time.Time) []models.ForecastEvent {
+	events := []models.ForecastEvent{}
+	for _, loan := range loans {
+		if loan.AccountID == nil {
+			continue
+		}
+		for _, inst := range fillLoanSchedule(&loan, payments[loan.ID]) {
+			if inst.Paid || !inst.DueDate.After(today) || inst.DueDate.After(end) {
+				continue
+			}
+			events = append(events, models.ForecastEvent{
+				Date:        inst.DueDate,
+				AccountID:   *loan.AccountID,
+				Amount:      -inst.Amount,
+				Kind:        "loan_payment",
+				Description: "Parcela " + strconv.Itoa(inst.Number) + "/" + strconv.Itoa(loan.TermMonths) + " - " + loan.Name,
+			})
+		}
+	}
+	return events
+}
+
+// simulateCashFlow aplica os eventos dia a dia de amanhã até end. Faturas de
+// cartão são calculadas durante a simulação: no fechamento guarda o valor devido
+// e no vencimento transfere esse valor da conta de pagamento para o cartão.
+func simulateCashFlow(accounts []forecastAccount, events []models.ForecastEvent, today, end time.Time) models.CashFlowForecast {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+
+	balances := make(map[int64]float64)
+	owed := make(map[int64]float64)
+	summaries := make([]models.AccountForecast, len(accounts))
+	summaryIndex := make(map[int64]int)
+	for i, acc := range accounts {
+		balances[acc.ID] = acc.Balance
+		owed[acc.ID] = acc.InitialOwed
+		summaryIndex[acc.ID] = i
+		summaries[i] = models.AccountForecast{
+			AccountID:      acc.ID,
+			Name:           acc.Name,
+			Type:           acc.Type,
+			StartBalance:   roundMoney(acc.Balance),
+			MinBalance:     roundMoney(acc.Balance),
+			MinBalanceDate: today,
+		}
+		if acc.Type != "cartao" && acc.Balance < 0 {
+			d := today
+			summaries[i].FirstNegativeDate = &d
+		}
+	}
+
+	forecast := models.CashFlowForecast{From: today, To: end, Accounts: summaries, Days: []models.ForecastDay{}}
+	next := 0
+	for date := today.AddDate(0, 0, 1); !date.After(end); date = date.AddDate(0, 0, 1) {
+		day := models.ForecastDay{Date: date, Balances: make(map[int64]float64)}
+
+		for ; next < len(events) && !events[next].Date.After(date); next++ {
+			ev := events[next]
+			if _, ok := balances[ev.AccountID]; !ok {
+				continue
+			}
+			balances[ev.AccountID] += ev.Amount
+			day.Events = append(day.Events, ev)
+		}
+
+		for _, acc := range accounts {
+			if acc.Type != "cartao" || acc.ClosingDay == nil || acc.DueDay == nil {
+				continue
+			}
+			if date.Equal(dateInMonth(date.Year(), date.Month(), *acc.DueDay)) && owed[acc.ID] > 0 && acc.PaymentAccountID != nil {
+				if _, ok := balances[*acc.PaymentAccountID]; ok {
+					amount := roundMoney(owed[acc.ID])
+					balances[*acc.PaymentAccountID] -= amount
+					balances[acc.ID] += amount
+					owed[acc.ID] = 0
+					description := "Fatura " + acc.Name
+					day.Events = append(day.Events,
+						models.ForecastEvent{Date: date, AccountID: *acc.PaymentAccountID, Amount: -amount, Kind: "card_payment", Description: description},
+						models.ForecastEvent{Date: date, AccountID: acc.ID, Amount: amount, Kind: "card_payment", Description: description},
+					)
+				}
+			}
+			if date.Equal(dateInMonth(date.Year(), date.Month(), *acc.ClosingDay)) {
+				owed[acc.ID] = 0
+				if balances[acc.ID] < 0 {
+					owed[acc.ID] = -balances[acc.ID]
+				}
+			}
+		}
+
+		for _, acc := range accounts {
+			balance := roundMoney(balances[acc.ID])
+			day.Balances[acc.ID] = balance
+			day.Total += balance
+
+			s := &forecast.Accounts[summaryIndex[acc.ID]]
+			if balance < s.MinBalance {
+				s.MinBalance = balance
+				s.MinBalanceDate = date
+			}
+			if acc.Type != "cartao" && balance < 0 && s.FirstNegativeDate == nil {
+				d := date
+				s.FirstNegativeDate = &d
+			}
+		}
+		day.Total = roundMoney(day.Total)
+		forecast.Days = append(forecast.Days, day)
+	}
+
+	for i, acc := range accounts {
+		forecast.Accounts[i].EndBalance = roundMoney(balances[acc.ID])
+	}
+	return forecast
+}
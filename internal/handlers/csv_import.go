@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const maxImportSize = 5 << 20 // 5 MB
+
+// ImportLineError descreve uma linha rejeitada em uma importação de CSV
+type ImportLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// readImportCSV lê o CSV enviado no corpo da requisição ou no campo "file" (multipart).
+// Com ponto e vírgula na primeira linha o separador é ";" e os números usam
+// decimal em vírgula (semicolon = true). Retorna status 0 quando está tudo certo.
+func readImportCSV(w http.ResponseWriter, r *http.Request) ([][]string, bool, int, string) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			return nil, false, http.StatusBadRequest, "Envie o CSV no campo file"
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, false, http.StatusBadRequest, "Arquivo muito grande ou inválido"
+	}
+
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	firstLine, _, _ := strings.Cut(text, "\n")
+	semicolon := strings.Contains(firstLine, ";")
+	if semicolon {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, false, http.StatusBadRequest, "CSV inválido: " + err.Error()
+	}
+	return records, semicolon, 0, ""
+}
+
+// skipImportLine indica linhas vazias e o cabeçalho (primeira coluna igual a header)
+func skipImportLine(i int, record []string, header string) bool {
+	if len(record) == 0 || (len(record) == 1 && strings.TrimSpace(record[0]) == "") {
+		return true
+	}
+	return i == 0 && strings.EqualFold(strings.TrimSpace(record[0]), header)
+}
+
+// parseImportDecimal lê um número do CSV; no formato com ponto e vírgula
+// aceita separador de milhar em ponto e decimal em vírgula (1.234,56)
+func parseImportDecimal(text string, semicolon bool) (float64, error) {
+	text = strings.TrimSpace(text)
+	if semicolon {
+		text = strings.ReplaceAll(strings.ReplaceAll(text, ".", ""), ",", ".")
+	}
+	return strconv.ParseFloat(text, 64)
+}
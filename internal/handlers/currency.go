@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const defaultCurrency = "BRL"
+
+// missingRateCode é o SQLSTATE levantado por to_base_currency (migrations/033) quando o
+// par não tem cotação para converter um valor para a moeda base
+const missingRateCode = "FX001"
+
+// rowQueryer é satisfeito por *sql.DB e *sql.Tx
+type rowQueryer interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// normalizeCurrency valida um código ISO 4217 (três letras) e o devolve em maiúsculas
+func normalizeCurrency(code string) (string, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return "", false
+		}
+	}
+	return code, true
+}
+
+// baseAmountSQL converte a coluna amount (na moeda da coluna currency) para a moeda
+// base do usuário na cotação da data da linha. Sem cotação cadastrada a consulta
+// falha (ver writeConversionError). userParam é o placeholder do usuário na consulta (ex: "$1").
+func baseAmountSQL(userParam string) string {
+	return "to_base_currency(" + userParam + ", amount, currency, date)"
+}
+
+// baseBalanceSQL converte o saldo atual de uma conta (alias a) para a moeda base
+func baseBalanceSQL(userParam string) string {
+	return "to_base_currency(" + userParam + ", a.balance, a.currency, CURRENT_DATE)"
+}
+
+// missingRateMessage devolve a mensagem para o cliente quando err veio de uma
+// conversão para a moeda base sem cotação (ex: "cotação ausente USD→BRL em 2025-01-31")
+func missingRateMessage(err error) (string, bool) {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == missingRateCode {
+		return pqErr.Message + missingRateHint, true
+	}
+	return "", false
+}
+
+const missingRateHint = "; cadastre a cotação em /v1/exchange-rates"
+
+// missingRateText é a mesma mensagem para conversões feitas fora do SQL
+func missingRateText(from, to string, date time.Time) string {
+	return fmt.Sprintf("cotação ausente %s→%s em %s", from, to, date.Format("2006-01-02")) + missingRateHint
+}
+
+// writeConversionError responde ao erro de uma consulta com valores na moeda base:
+// falta de cotação vira 422 com o par e a data; qualquer outro erro, 500 com msg
+func writeConversionError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if missing, ok := missingRateMessage(err); ok {
+		http.Error(w, missing, http.StatusUnprocessableEntity)
+		return
+	}
+	http.Error(w, msg, http.StatusInternalServerError)
+	slog.ErrorContext(r.Context(), msg, "error", err)
+}
+
+func userBaseCurrency(ctx context.Context, q rowQueryer, userID int) (string, error) {
+	var currency string
+	err := q.QueryRowContext(ctx, `SELECT user_base_currency($1)`, userID).Scan(&currency)
+	return currency, err
+}
+
+// resolveTransactionCurrency define a moeda de um gasto ou renda (padrão: a da conta)
+// e, quando ela difere da moeda da conta, o valor a lançar no saldo da conta:
+// o informado pelo cliente (ex: valor da fatura) ou a conversão pela cotação da data.
+// Retorna status 0 quando está tudo certo.
+func resolveTransactionCurrency(ctx context.Context, q rowQueryer, userID int, accountID int64, currency *string, amount float64, accountAmount *float64, date time.Time) (*float64, int, string) {
+	var accCurrency string
+	err := q.QueryRowContext(ctx, `SELECT currency FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, accountID, userID).Scan(&accCurrency)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusForbidden, "Conta inválida"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao buscar moeda da conta"
+	}
+
+	if strings.TrimSpace(*currency) == "" {
+		*currency = accCurrency
+	}
+	code, ok := normalizeCurrency(*currency)
+	if !ok {
+		return nil, http.StatusBadRequest, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)"
+	}
+	*currency = code
+
+	if code == accCurrency {
+		return nil, 0, ""
+	}
+	if accountAmount != nil {
+		if *accountAmount <= 0 {
+			return nil, http.StatusBadRequest, "Valor na moeda da conta deve ser positivo"
+		}
+		v := roundMoney(*accountAmount)
+		return &v, 0, ""
+	}
+
+	var converted sql.NullFloat64
+	err = q.QueryRowContext(ctx, `SELECT convert_amount($1, $2, $3, $4, $5)`, userID, amount, code, accCurrency, date).Scan(&converted)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Erro ao converter moeda"
+	}
+	if !converted.Valid {
+		return nil, http.StatusBadRequest, "Sem cotação de " + code + " para " + accCurrency + "; cadastre em /exchange-rates ou informe account_amount"
+	}
+	v := converted.Float64
+	return &v, 0, ""
+}
+
+// accountEffect é o valor que a transação lança no saldo da conta
+func accountEffect(amount float64, accountAmount *float64) float64 {
+	if accountAmount != nil {
+		return *accountAmount
+	}
+	return amount
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type ExchangeRateHandler struct {
+	DB *sql.DB
+}
+
+type exchangeRateRequest struct {
+	Currency      string  `json:"currency"`
+	QuoteCurrency string  `json:"quote_currency"` // padrão: moeda base do usuário
+	Date          string  `json:"date"`           // padrão: hoje
+	Rate          float64 `json:"rate"`
+}
+
+// validateRate normaliza e valida uma cotação; baseCurrency é usada quando a
+// moeda de cotação não é informada
+func validateRate(req exchangeRateRequest, baseCurrency string) (models.ExchangeRate, error) {
+	var rate models.ExchangeRate
+
+	currency, ok := normalizeCurrency(req.Currency)
+	if !ok {
+		return rate, errors.New("Moeda inválida, use o código ISO de três letras (ex: USD)")
+	}
+	quote := baseCurrency
+	if strings.TrimSpace(req.QuoteCurrency) != "" {
+		if quote, ok = normalizeCurrency(req.QuoteCurrency); !ok {
+			return rate, errors.New("Moeda de cotação inválida, use o código ISO de três letras (ex: BRL)")
+		}
+	}
+	if currency == quote {
+		return rate, errors.New("Moeda e moeda de cotação devem ser diferentes")
+	}
+	if req.Rate <= 0 {
+		return rate, errors.New("Cotação deve ser maior que zero")
+	}
+
+	date := time.Now().UTC().Truncate(24 * time.Hour)
+	if strings.TrimSpace(req.Date) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(req.Date))
+		if err != nil {
+			return rate, errors.New("Data inválida, use YYYY-MM-DD")
+		}
+		date = parsed
+	}
+
+	rate.Currency = currency
+	rate.QuoteCurrency = quote
+	rate.Date = date
+	rate.Rate = req.Rate
+	return rate, nil
+}
+
+// upsertRate grava a cotação, substituindo a do mesmo par e dia. Retorna a cotação
+// substituída (nil quando é nova) para a auditoria.
+func upsertRate(ctx context.Context, q rowQueryer, userID int, rate *models.ExchangeRate) (json.RawMessage, error) {
+	var before []byte
+	err := q.QueryRowContext(ctx, `
+		WITH old AS (
+			SELECT row_to_json(t) AS data FROM exchange_rates t
+			WHERE user_id = $1 AND currency = $2 AND quote_currency = $3 AND date = $4
+		)
+		INSERT INTO exchange_rates (user_id, currency, quote_currency, date, rate)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, currency, quote_currency, date) DO UPDATE SET rate = EXCLUDED.rate
+		RETURNING id, (SELECT data FROM old)
+	`, userID, rate.Currency, rate.QuoteCurrency, rate.Date, rate.Rate).Scan(&rate.ID, &before)
+	return before, err
+}
+
+// invalidateNetWorth descarta todos os snapshots de patrimônio, para mudanças que
+// afetam o histórico inteiro (cotações, saldo atual de uma conta); eles são refeitos
+// na próxima consulta do histórico. Roda depois do commit, então
+// não é interrompida se o cliente desconectar.
+func invalidateNetWorth(ctx context.Context, db *sql.DB, userID int) error {
+	_, err := db.ExecContext(context.WithoutCancel(ctx), `DELETE FROM net_worth_snapshots WHERE user_id = $1`, userID)
+	return err
+}
+
+// GetExchangeRates lista as cotações do usuário
+// Parâmetros opcionais: currency, quote_currency, from, to (YYYY-MM-DD)
+func (h *ExchangeRateHandler) GetExchangeRates(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	query := r.URL.Query()
+
+	baseQuery := `SELECT id, currency, quote_currency, date, rate FROM exchange_rates WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	for param, column := range map[string]string{"currency": "currency", "quote_currency": "quote_currency"} {
+		if v := query.Get(param); v != "" {
+			code, ok := normalizeCurrency(v)
+			if !ok {
+				http.Error(w, "Moeda inválida em "+param, http.StatusBadRequest)
+				return
+			}
+			baseQuery += " AND " + column + " = $" + strconv.Itoa(len(args)+1)
+			args = append(args, code)
+		}
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		if v := query.Get(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			baseQuery += " AND date " + op + " $" + strconv.Itoa(len(args)+1)
+			args = append(args, date)
+		}
+	}
+	baseQuery += " ORDER BY date DESC, currency, quote_currency"
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotações", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar cotações", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	rates := []models.ExchangeRate{}
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(&rate.ID, &rate.Currency, &rate.QuoteCurrency, &rate.Date, &rate.Rate); err != nil {
+			http.Error(w, "Erro ao ler cotações", http.StatusInternalServerError)
+			return
+		}
+		rates = append(rates, rate)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rates)
+}
+
+// CreateExchangeRate cadastra (ou substitui) a cotação de um par em uma data
+func (h *ExchangeRateHandler) CreateExchangeRate(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req exchangeRateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(r.Context(), h.DB, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar moeda base", http.StatusInternalServerError)
+		return
+	}
+	rate, err := validateRate(req, baseCurrency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := upsertRate(r.Context(), tx, userID, &rate)
+	if err != nil {
+		http.Error(w, "Erro ao salvar cotação", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao salvar cotação", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "exchange_rate", rate.ID, upsertAction(before), before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rate)
+}
+
+// ImportExchangeRates importa cotações de um CSV (corpo da requisição ou campo
+// "file" em multipart) com as colunas date, currency, quote_currency, rate.
+// Aceita vírgula ou ponto e vírgula como separador (neste caso com decimal em vírgula)
+// e ignora a linha de cabeçalho. Linhas inválidas são reportadas e as demais importadas.
+func (h *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	records, semicolon, status, msg := readImportCSV(w, r)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(r.Context(), h.DB, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar moeda base", http.StatusInternalServerError)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	importErrors := []ImportLineError{}
+	for i, record := range records {
+		line := i + 1
+		if skipImportLine(i, record, "date") {
+			continue
+		}
+		if len(record) < 4 {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: "Esperado: date, currency, quote_currency, rate"})
+			continue
+		}
+
+		value, err := parseImportDecimal(record[3], semicolon)
+		if err != nil {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: "Cotação inválida"})
+			continue
+		}
+
+		rate, err := validateRate(exchangeRateRequest{
+			Date:          record[0],
+			Currency:      record[1],
+			QuoteCurrency: record[2],
+			Rate:          value,
+		}, baseCurrency)
+		if err != nil {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+		before, err := upsertRate(r.Context(), tx, userID, &rate)
+		if err != nil {
+			http.Error(w, "Erro ao salvar cotações", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao salvar cotações", "error", err)
+			return
+		}
+		if err := recordAudit(tx, r, userID, "exchange_rate", rate.ID, upsertAction(before), before); err != nil {
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+			return
+		}
+		imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if imported > 0 {
+		if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": imported,
+		"errors":   importErrors,
+	})
+}
+
+func (h *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "exchange_rate", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotação", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM exchange_rates WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao deletar cotação", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "exchange_rate", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,47 +3,47 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
 	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
 )
 
 type ExpenseHandler struct {
 	DB *sql.DB
 }
 
-func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Description   string  `json:"description"`
-		Amount        float64 `json:"amount"`
-		Category      string  `json:"category"`
-		Group         string  `json:"group"`
-		PaymentMethod string  `json:"payment_method"`
-		Date          string  `json:"date"`
-		AccountID     *int64  `json:"account_id"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
-		return
-	}
+// expenseRequest é o corpo aceito na criação e na atualização de gastos
+type expenseRequest struct {
+	Description   string   `json:"description"`
+	Amount        float64  `json:"amount"`
+	Category      string   `json:"category"`
+	Group         string   `json:"group"`
+	PaymentMethod string   `json:"payment_method"`
+	Date          string   `json:"date"`
+	AccountID     *int64   `json:"account_id"`
+	Tags          []string `json:"tags"`
+
+	Splits []models.ExpenseSplit `json:"splits"`
+
+	Currency      string   `json:"currency"`       // padrão: moeda da conta
+	AccountAmount *float64 `json:"account_amount"` // valor na moeda da conta, quando difere
+}
 
+// newExpense monta o gasto a partir do corpo, com grupo padrão e divisões validadas.
+// Retorna status 0 quando está tudo certo.
+func newExpense(req expenseRequest) (models.Expense, []models.ExpenseSplit, int, string) {
 	expenseDate := time.Now().UTC()
 	if strings.TrimSpace(req.Date) != "" {
 		parsed, err := time.Parse("2006-01-02", req.Date)
 		if err != nil {
-			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
-			return
+			return models.Expense{}, nil, http.StatusBadRequest, "Data inválida, use YYYY-MM-DD"
 		}
 		expenseDate = parsed
 	}
@@ -56,9 +56,10 @@ func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		PaymentMethod: req.PaymentMethod,
 		Date:          expenseDate,
 		AccountID:     req.AccountID,
+		Tags:          normalizeTags(req.Tags),
 	}
 
-	// Default and validate group
+	// Default and validate group (guard invalid values to satisfy check constraint)
 	if strings.TrimSpace(expense.Group) == "" {
 		expense.Group = "essencial"
 	}
@@ -68,60 +69,128 @@ func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
 		expense.Group = "essencial"
 	}
 
+	// Gasto dividido: o gasto assume a categoria e o grupo da maior divisão
+	splits, err := validateSplits(req.Splits, expense.Amount)
+	if err != nil {
+		return models.Expense{}, nil, http.StatusBadRequest, err.Error()
+	}
+	if splits != nil {
+		primary := primarySplit(splits)
+		expense.Category = primary.Category
+		expense.Group = primary.Group
+	}
+	return expense, splits, 0, ""
+}
+
+func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req expenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
+		return
+	}
+
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
+	// Start transaction
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	expense, status, msg := h.createExpense(tx, r, userID, req)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	metrics.TransactionsCreated.WithLabelValues("expense").Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(expense)
+}
+
+// createExpense grava o gasto e debita a conta dentro de tx (também usado pelo lote).
+// Retorna status 0 quando está tudo certo.
+func (h *ExpenseHandler) createExpense(tx *sql.Tx, r *http.Request, userID int, req expenseRequest) (models.Expense, int, string) {
+	expense, splits, status, msg := newExpense(req)
+	if status != 0 {
+		return expense, status, msg
+	}
+
 	// Se não tem account_id, cria/busca Carteira Geral
 	if expense.AccountID == nil {
 		accountHandler := &AccountHandler{DB: h.DB}
-		defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(userID)
+		defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(r.Context(), userID)
 		if err != nil {
-			http.Error(w, "Erro ao criar conta padrão", http.StatusInternalServerError)
-			fmt.Println("Erro ao criar conta padrão:", err)
-			return
+			slog.ErrorContext(r.Context(), "Erro ao criar conta padrão", "error", err)
+			return expense, http.StatusInternalServerError, "Erro ao criar conta padrão"
 		}
 		expense.AccountID = &defaultAccountID
 	}
 
-	// Start transaction
-	tx, err := h.DB.Begin()
-	if err != nil {
-		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
-		return
+	expense.Currency = req.Currency
+	accountAmount, status, msg := resolveTransactionCurrency(r.Context(), tx, userID, *expense.AccountID, &expense.Currency, expense.Amount, req.AccountAmount, expense.Date)
+	if status != 0 {
+		return expense, status, msg
 	}
-	defer tx.Rollback()
+	expense.AccountAmount = accountAmount
 
 	query := `
-		INSERT INTO expenses (description, amount, category, "group", payment_method, date, user_id, account_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+		INSERT INTO expenses (description, amount, category, "group", payment_method, date, user_id, account_id, currency, account_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) 
 		RETURNING id;
 	`
 
-	err = tx.QueryRow(query, expense.Description, expense.Amount, expense.Category, expense.Group, expense.PaymentMethod, expense.Date, userID, expense.AccountID).Scan(&expense.ID)
+	err := tx.QueryRowContext(r.Context(), query, expense.Description, expense.Amount, expense.Category, expense.Group, expense.PaymentMethod, expense.Date, userID, expense.AccountID, expense.Currency, expense.AccountAmount).Scan(&expense.ID)
 	if err != nil {
-		http.Error(w, "Erro ao inserir gasto no banco", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao inserir gasto no banco", "error", err)
+		return expense, http.StatusInternalServerError, "Erro ao inserir gasto no banco"
 	}
 
 	// Update account balance if account_id is provided
 	if expense.AccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, expense.Amount, expense.AccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, accountEffect(expense.Amount, expense.AccountAmount), expense.AccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
-			fmt.Println("Erro:", err)
-			return
+			slog.ErrorContext(r.Context(), "Erro ao atualizar saldo da conta", "error", err)
+			return expense, http.StatusInternalServerError, "Erro ao atualizar saldo da conta"
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, expense.Date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return expense, http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(expense)
+	if err = setTransactionTags(r.Context(), tx, userID, "expense", expense.ID, expense.Tags); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao salvar tags", "error", err)
+		return expense, http.StatusInternalServerError, "Erro ao salvar tags"
+	}
+
+	if expense.Splits, err = setExpenseSplits(r.Context(), tx, expense.ID, splits); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao salvar divisões do gasto", "error", err)
+		return expense, http.StatusInternalServerError, "Erro ao salvar divisões do gasto"
+	}
+
+	if err = recordAudit(tx, r, userID, "expense", expense.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return expense, http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return expense, 0, ""
 }
 
 func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
@@ -135,7 +204,7 @@ func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 	monthParam := r.URL.Query().Get("month")
 	yearParam := r.URL.Query().Get("year")
 
-	baseQuery := `SELECT id, description, amount, category, "group", payment_method, date, account_id FROM expenses WHERE user_id = $1`
+	baseQuery := `SELECT id, description, amount, category, "group", payment_method, date, account_id, currency, account_amount FROM expenses WHERE user_id = $1 AND deleted_at IS NULL`
 	args := []interface{}{userID}
 
 	if monthParam != "" {
@@ -150,12 +219,36 @@ func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 		args = append(args, yearVal)
 	}
 
-	baseQuery += " ORDER BY date DESC"
+	if tags := parseTagFilter(r); tags != nil {
+		baseQuery += tagFilterClause("expense", "id", len(args)+1)
+		args = append(args, pq.Array(tags))
+	}
+
+	// Em /v1/expenses/{id} retorna só o gasto
+	single := r.PathValue("id")
+	if single != "" {
+		singleID, err := strconv.ParseInt(single, 10, 64)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, singleID)
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var pageClause string
+	pageClause, args = page.keysetClause(args)
+	baseQuery += pageClause
 
-	rows, err := h.DB.Query(baseQuery, args...)
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar gastos no banco", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
+		slog.ErrorContext(r.Context(), "Erro ao buscar gastos no banco", "error", err)
 		return
 	}
 	defer rows.Close()
@@ -163,7 +256,7 @@ func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 	var expenses []models.Expense
 	for rows.Next() {
 		var expense models.Expense
-		err := rows.Scan(&expense.ID, &expense.Description, &expense.Amount, &expense.Category, &expense.Group, &expense.PaymentMethod, &expense.Date, &expense.AccountID)
+		err := rows.Scan(&expense.ID, &expense.Description, &expense.Amount, &expense.Category, &expense.Group, &expense.PaymentMethod, &expense.Date, &expense.AccountID, &expense.Currency, &expense.AccountAmount)
 		if err != nil {
 			http.Error(w, "Erro ao ler dados do banco", http.StatusInternalServerError)
 			return
@@ -171,7 +264,61 @@ func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
 		expenses = append(expenses, expense)
 	}
 
+	// Uma linha além do limite indica que existe próxima página
+	var nextCursor *string
+	if page.Enabled && len(expenses) > page.Limit {
+		expenses = expenses[:page.Limit]
+		last := expenses[len(expenses)-1]
+		c := encodeCursor(pageCursor{Date: last.Date, ID: last.ID})
+		nextCursor = &c
+	}
+
+	ids := make([]int64, len(expenses))
+	for i, e := range expenses {
+		ids[i] = e.ID
+	}
+	tags, err := loadTransactionTags(r.Context(), h.DB, userID, "expense", ids)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tags", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar tags", "error", err)
+		return
+	}
+	splits, err := loadExpenseSplits(r.Context(), h.DB, ids)
+	if err != nil {
+		http.Error(w, "Erro ao buscar divisões dos gastos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar divisões dos gastos", "error", err)
+		return
+	}
+	for i := range expenses {
+		expenses[i].Tags = tags[expenses[i].ID]
+		if expenses[i].Tags == nil {
+			expenses[i].Tags = []string{}
+		}
+		expenses[i].Splits = splits[expenses[i].ID]
+		if expenses[i].Splits == nil {
+			expenses[i].Splits = []models.ExpenseSplit{}
+		}
+	}
+
+	if single != "" {
+		if len(expenses) == 0 {
+			http.Error(w, "Gasto não encontrado", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(expenses[0])
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	if page.Enabled {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":       expenses,
+			"next_cursor": nextCursor,
+			"limit":       page.Limit,
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(expenses)
 }
 
@@ -181,114 +328,183 @@ func (h *ExpenseHandler) UpdateExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
 		return
 	}
+	expenseID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
 
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	var req struct {
-		Description   string  `json:"description"`
-		Amount        float64 `json:"amount"`
-		Category      string  `json:"category"`
-		Group         string  `json:"group"`
-		PaymentMethod string  `json:"payment_method"`
-		Date          string  `json:"date"`
-		AccountID     *int64  `json:"account_id"`
-	}
-
+	var req expenseRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
 		return
 	}
 
-	expenseDate := time.Now().UTC()
-	if strings.TrimSpace(req.Date) != "" {
-		parsed, err := time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-		expenseDate = parsed
-	}
-
-	expense := models.Expense{
-		Description:   req.Description,
-		Amount:        req.Amount,
-		Category:      req.Category,
-		Group:         req.Group,
-		PaymentMethod: req.PaymentMethod,
-		Date:          expenseDate,
-		AccountID:     req.AccountID,
+	// Start transaction
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	if strings.TrimSpace(expense.Group) == "" {
-		expense.Group = "essencial"
+	if status, msg := checkPatchBase(r.Context(), tx, id, userID); status != 0 {
+		http.Error(w, msg, status)
+		return
 	}
 
-	// Guard invalid values to satisfy check constraint
-	switch expense.Group {
-	case "essencial", "lazer", "investimento":
-	default:
-		expense.Group = "essencial"
+	if status, msg := h.updateExpense(tx, r, userID, expenseID, req); status != 0 {
+		http.Error(w, msg, status)
+		return
 	}
 
-	// Start transaction
-	tx, err := h.DB.Begin()
+	err = tx.Commit()
 	if err != nil {
-		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
 		return
 	}
-	defer tx.Rollback()
 
-	// Get old expense data
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Gasto atualizado com sucesso"}`))
+}
+
+// updateExpense altera o gasto dentro de tx, movendo o efeito no saldo da conta
+// antiga para a nova. Retorna status 0 quando está tudo certo.
+func (h *ExpenseHandler) updateExpense(tx *sql.Tx, r *http.Request, userID int, expenseID int64, req expenseRequest) (int, string) {
+	expense, splits, status, msg := newExpense(req)
+	if status != 0 {
+		return status, msg
+	}
+
+	// Get old expense data (amount already in the account currency)
 	var oldAmount float64
 	var oldAccountID *int64
-	err = tx.QueryRow(`SELECT amount, account_id FROM expenses WHERE id = $1 AND user_id = $2`, id, userID).Scan(&oldAmount, &oldAccountID)
+	var oldCurrency string
+	var oldDate time.Time
+	err := tx.QueryRowContext(r.Context(), `SELECT COALESCE(account_amount, amount), account_id, currency, date FROM expenses WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, expenseID, userID).Scan(&oldAmount, &oldAccountID, &oldCurrency, &oldDate)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Gasto não encontrado"
+	}
 	if err != nil {
-		http.Error(w, "Erro ao buscar gasto", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar gasto"
+	}
+	before, err := auditSnapshot(r.Context(), tx, userID, "expense", expenseID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar gasto"
+	}
+
+	// Sem o campo currency mantém a moeda atual do gasto
+	expense.Currency = req.Currency
+	if strings.TrimSpace(expense.Currency) == "" {
+		expense.Currency = oldCurrency
+	}
+	if expense.AccountID != nil {
+		accountAmount, status, msg := resolveTransactionCurrency(r.Context(), tx, userID, *expense.AccountID, &expense.Currency, expense.Amount, req.AccountAmount, expense.Date)
+		if status != 0 {
+			return status, msg
+		}
+		expense.AccountAmount = accountAmount
+	} else if code, ok := normalizeCurrency(expense.Currency); ok {
+		expense.Currency = code
+	} else {
+		return http.StatusBadRequest, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)"
+	}
+
+	// Sem o campo splits as divisões atuais são mantidas, então precisam continuar
+	// somando o novo valor
+	if req.Splits == nil {
+		var splitCount int
+		var splitTotal float64
+		err = tx.QueryRowContext(r.Context(), `SELECT COUNT(*), COALESCE(SUM(amount), 0) FROM expense_splits WHERE expense_id = $1`, expenseID).Scan(&splitCount, &splitTotal)
+		if err != nil {
+			return http.StatusInternalServerError, "Erro ao buscar divisões do gasto"
+		}
+		if splitCount > 0 && roundMoney(splitTotal) != roundMoney(expense.Amount) {
+			return http.StatusBadRequest, "A soma das divisões deve ser igual ao valor do gasto"
+		}
 	}
 
 	// Update expense
-	query := `UPDATE expenses SET description = $1, amount = $2, category = $3, "group" = $4, payment_method = $5, date = $6, account_id = $7 WHERE id = $8 AND user_id = $9`
-	_, err = tx.Exec(query, expense.Description, expense.Amount, expense.Category, expense.Group, expense.PaymentMethod, expense.Date, expense.AccountID, id, userID)
+	query := `UPDATE expenses SET description = $1, amount = $2, category = $3, "group" = $4, payment_method = $5, date = $6, account_id = $7, currency = $8, account_amount = $9 WHERE id = $10 AND user_id = $11`
+	_, err = tx.ExecContext(r.Context(), query, expense.Description, expense.Amount, expense.Category, expense.Group, expense.PaymentMethod, expense.Date, expense.AccountID, expense.Currency, expense.AccountAmount, expenseID, userID)
 	if err != nil {
-		http.Error(w, "Erro ao atualizar gasto", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao atualizar gasto", "error", err)
+		return http.StatusInternalServerError, "Erro ao atualizar gasto"
 	}
 
 	// Adjust account balances
 	// Restore old account balance
 	if oldAccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, oldAmount, oldAccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, oldAmount, oldAccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta antiga", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da conta antiga"
 		}
 	}
 
 	// Deduct from new account balance
 	if expense.AccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, expense.Amount, expense.AccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, accountEffect(expense.Amount, expense.AccountAmount), expense.AccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da nova conta", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da nova conta"
 		}
 	}
 
-	err = tx.Commit()
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, oldDate, expense.Date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return http.StatusInternalServerError, "Erro ao invalidar patrimônio"
+	}
+
+	// Tags are only replaced when the field is sent
+	if req.Tags != nil {
+		if err = setTransactionTags(r.Context(), tx, userID, "expense", expenseID, req.Tags); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao salvar tags", "error", err)
+			return http.StatusInternalServerError, "Erro ao salvar tags"
+		}
+	}
+
+	// Divisões também: lista vazia transforma de volta em gasto de uma categoria
+	if req.Splits != nil {
+		if _, err = setExpenseSplits(r.Context(), tx, expenseID, splits); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao salvar divisões do gasto", "error", err)
+			return http.StatusInternalServerError, "Erro ao salvar divisões do gasto"
+		}
+	}
+
+	// Parcela de empréstimo: o novo valor/data muda a divisão juros/amortização
+	loanID, err := linkedLoan(r.Context(), tx, expenseID)
 	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar empréstimo do gasto"
+	}
+	if loanID != nil {
+		if status, msg := checkLoanCurrency(r.Context(), tx, *loanID, expense.Currency); status != 0 {
+			return status, msg
+		}
+		if err = recalcLoan(r.Context(), tx, *loanID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao recalcular empréstimo", "error", err)
+			return http.StatusInternalServerError, "Erro ao recalcular empréstimo"
+		}
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message": "Gasto atualizado com sucesso"}`))
+	if err = recordAudit(tx, r, userID, "expense", expenseID, auditUpdate, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return 0, ""
+}
+
+// PatchExpense altera só os campos enviados do gasto (PATCH /v1/expenses/{id})
+func (h *ExpenseHandler) PatchExpense(w http.ResponseWriter, r *http.Request) {
+	mergePatch(w, r, h.DB, expensePatchSQL, "Gasto não encontrado", h.UpdateExpense)
 }
 
 func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
@@ -297,53 +513,99 @@ func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
 		return
 	}
+	expenseID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
 
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
 	// Start transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
 		return
 	}
 	defer tx.Rollback()
 
+	if status, msg := h.deleteExpense(tx, r, userID, expenseID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteExpense move o gasto para a lixeira dentro de tx, estornando o saldo da conta.
+// Retorna status 0 quando está tudo certo.
+func (h *ExpenseHandler) deleteExpense(tx *sql.Tx, r *http.Request, userID int, expenseID int64) (int, string) {
 	// Get expense data before deleting to restore account balance
 	var amount float64
 	var accountID *int64
-	err = tx.QueryRow(`SELECT amount, account_id FROM expenses WHERE id = $1 AND user_id = $2`, id, userID).Scan(&amount, &accountID)
+	var date time.Time
+	err := tx.QueryRowContext(r.Context(), `SELECT COALESCE(account_amount, amount), account_id, date FROM expenses WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, expenseID, userID).Scan(&amount, &accountID, &date)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Gasto não encontrado"
+	}
 	if err != nil {
-		http.Error(w, "Erro ao buscar gasto", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar gasto"
 	}
 
-	// Delete expense
-	_, err = tx.Exec(`DELETE FROM expenses WHERE id = $1 AND user_id = $2`, id, userID)
+	before, err := auditSnapshot(r.Context(), tx, userID, "expense", expenseID)
 	if err != nil {
-		http.Error(w, "Erro ao deletar gasto", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar gasto"
+	}
+
+	// Parcela de empréstimo: o pagamento deixa de contar enquanto o gasto está na lixeira
+	loanID, err := linkedLoan(r.Context(), tx, expenseID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar empréstimo do gasto"
+	}
+
+	// Move to trash (anexos ficam até a limpeza definitiva)
+	_, err = tx.ExecContext(r.Context(), `UPDATE expenses SET deleted_at = NOW() WHERE id = $1 AND user_id = $2`, expenseID, userID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao deletar gasto"
+	}
+
+	if loanID != nil {
+		if err = recalcLoan(r.Context(), tx, *loanID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao recalcular empréstimo", "error", err)
+			return http.StatusInternalServerError, "Erro ao recalcular empréstimo"
+		}
 	}
 
 	// Restore account balance if account_id exists
 	if accountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, amount, accountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, amount, accountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da conta"
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if err = recordAudit(tx, r, userID, "expense", expenseID, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return 0, ""
 }
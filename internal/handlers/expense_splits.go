@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
+)
+
+// expenseLines expõe os gastos como linhas de categoria: um gasto dividido vira uma
+// linha por divisão, os demais continuam com uma linha só. Use no lugar de
+// "expenses" nas consultas que agregam por categoria ou grupo (já ignora a lixeira).
+const expenseLines = `(
+	SELECT e.id, e.user_id, e.description, e.payment_method, e.date, e.account_id, e.currency,
+		COALESCE(s.category, e.category) AS category,
+		COALESCE(s."group", e."group") AS "group",
+		COALESCE(s.amount, e.amount) AS amount
+	FROM expenses e
+	LEFT JOIN expense_splits s ON s.expense_id = e.id
+	WHERE e.deleted_at IS NULL
+)`
+
+// normalizeGroup aplica o grupo padrão aos valores vazios ou inválidos
+func normalizeGroup(group string) string {
+	switch group {
+	case "essencial", "lazer", "investimento":
+		return group
+	default:
+		return "essencial"
+	}
+}
+
+// validateSplits confere as divisões de um gasto: cada linha precisa de categoria e
+// valor positivo, e a soma deve bater com o total. Retorna as divisões normalizadas.
+func validateSplits(splits []models.ExpenseSplit, total float64) ([]models.ExpenseSplit, error) {
+	if len(splits) == 0 {
+		return nil, nil
+	}
+	if len(splits) == 1 {
+		return nil, errors.New("Divisão precisa de pelo menos duas linhas")
+	}
+
+	var sum float64
+	normalized := make([]models.ExpenseSplit, len(splits))
+	for i, s := range splits {
+		s.Category = strings.TrimSpace(s.Category)
+		s.Description = strings.TrimSpace(s.Description)
+		if s.Category == "" {
+			return nil, errors.New("Categoria é obrigatória em cada divisão")
+		}
+		if s.Amount <= 0 {
+			return nil, errors.New("Valor de cada divisão deve ser positivo")
+		}
+		s.Amount = roundMoney(s.Amount)
+		s.Group = normalizeGroup(s.Group)
+		sum += s.Amount
+		normalized[i] = s
+	}
+	if math.Abs(roundMoney(sum)-roundMoney(total)) > 0.005 {
+		return nil, errors.New("A soma das divisões deve ser igual ao valor do gasto")
+	}
+	return normalized, nil
+}
+
+// primarySplit é a divisão de maior valor; sua categoria e grupo ficam no próprio
+// gasto para as telas que mostram uma categoria só
+func primarySplit(splits []models.ExpenseSplit) models.ExpenseSplit {
+	primary := splits[0]
+	for _, s := range splits[1:] {
+		if s.Amount > primary.Amount {
+			primary = s
+		}
+	}
+	return primary
+}
+
+// setExpenseSplits substitui as divisões de um gasto (lista vazia remove a divisão)
+func setExpenseSplits(ctx context.Context, tx *sql.Tx, expenseID int64, splits []models.ExpenseSplit) ([]models.ExpenseSplit, error) {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM expense_splits WHERE expense_id = $1`, expenseID); err != nil {
+		return nil, err
+	}
+
+	saved := []models.ExpenseSplit{}
+	for _, s := range splits {
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO expense_splits (expense_id, description, category, "group", amount)
+			VALUES ($1, NULLIF($2, ''), $3, $4, $5)
+			RETURNING id
+		`, expenseID, s.Description, s.Category, s.Group, s.Amount).Scan(&s.ID)
+		if err != nil {
+			return nil, err
+		}
+		saved = append(saved, s)
+	}
+	return saved, nil
+}
+
+// loadExpenseSplits busca as divisões de vários gastos de uma vez
+func loadExpenseSplits(ctx context.Context, db *sql.DB, ids []int64) (map[int64][]models.ExpenseSplit, error) {
+	splits := make(map[int64][]models.ExpenseSplit)
+	if len(ids) == 0 {
+		return splits, nil
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT expense_id, id, COALESCE(description, ''), category, "group", amount
+		FROM expense_splits
+		WHERE expense_id = ANY($1)
+		ORDER BY expense_id, id
+	`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var expenseID int64
+		var s models.ExpenseSplit
+		if err := rows.Scan(&expenseID, &s.ID, &s.Description, &s.Category, &s.Group, &s.Amount); err != nil {
+			return nil, err
+		}
+		splits[expenseID] = append(splits[expenseID], s)
+	}
+	return splits, rows.Err()
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type ForecastHandler struct {
+	DB *sql.DB
+}
+
+const maxForecastHorizonMonths = 24
+
+// GetCashFlowForecast projeta o saldo de cada conta dia a dia nos próximos N meses
+// usando lançamentos futuros, recorrências detectadas, faturas de cartão, aportes programados
+// e parcelas de empréstimos
+// Parâmetros: months (padrão 3, máximo 24)
+func (h *ForecastHandler) GetCashFlowForecast(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	months := 3
+	if v := r.URL.Query().Get("months"); v != "" {
+		m, err := strconv.Atoi(v)
+		if err != nil || m < 1 || m > maxForecastHorizonMonths {
+			http.Error(w, "Parâmetro months deve estar entre 1 e 24", http.StatusBadRequest)
+			return
+		}
+		months = m
+	}
+
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := today.AddDate(0, months, 0)
+
+	accounts, err := h.loadForecastAccounts(r.Context(), userID, today)
+	if err != nil {
+		http.Error(w, "Erro ao buscar contas", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar contas", "error", err)
+		return
+	}
+
+	events, err := h.loadFutureTransactions(r.Context(), userID, today, end)
+	if err != nil {
+		http.Error(w, "Erro ao buscar lançamentos futuros", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar lançamentos futuros", "error", err)
+		return
+	}
+
+	txs, err := h.loadRecurringCandidates(r.Context(), userID, today, end)
+	if err != nil {
+		http.Error(w, "Erro ao buscar histórico", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar histórico", "error", err)
+		return
+	}
+	patterns, recurringEvents := detectRecurring(txs, today, end)
+	events = append(events, recurringEvents...)
+
+	goals, err := h.loadGoalSchedules(r.Context(), userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar metas", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar metas", "error", err)
+		return
+	}
+	events = append(events, scheduleGoalContributions(goals, today, end)...)
+
+	loans, payments, err := loadLoans(r.Context(), h.DB, userID, 0)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar empréstimos", "error", err)
+		return
+	}
+	events = append(events, scheduleLoanInstallments(loans, payments, today, end)...)
+
+	forecast := simulateCashFlow(accounts, events, today, end)
+	forecast.Recurring = patterns
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(forecast)
+}
+
+// loadForecastAccounts busca as contas com o saldo do fim do dia de hoje (o saldo
+// gravado já inclui lançamentos futuros) e a fatura fechada ainda não vencida
+func (h *ForecastHandler) loadForecastAccounts(ctx context.Context, userID int, today time.Time) ([]forecastAccount, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		WITH `+accountEffectsCTE+`
+		SELECT a.id, a.name, a.type, a.statement_closing_day, a.statement_due_day, a.payment_account_id,
+			a.balance - COALESCE((SELECT SUM(delta) FROM daily WHERE daily.account_id = a.id AND daily.date > $2::date), 0)
+		FROM accounts a
+		WHERE a.user_id = $1 AND a.deleted_at IS NULL
+		ORDER BY a.created_at
+	`, userID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []forecastAccount{}
+	for rows.Next() {
+		var acc forecastAccount
+		if err := rows.Scan(&acc.ID, &acc.Name, &acc.Type, &acc.ClosingDay, &acc.DueDay, &acc.PaymentAccountID, &acc.Balance); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Fatura fechada cujo vencimento ainda não chegou: saldo do cartão no último fechamento
+	for i := range accounts {
+		acc := &accounts[i]
+		if acc.Type != "cartao" || acc.ClosingDay == nil || acc.DueDay == nil {
+			continue
+		}
+		lastClosing := previousOccurrence(*acc.ClosingDay, today)
+		if !nextOccurrence(*acc.DueDay, lastClosing).After(today) {
+			continue
+		}
+
+		var sinceClosing float64
+		err := h.DB.QueryRowContext(ctx, `
+			WITH `+accountEffectsCTE+`
+			SELECT COALESCE(SUM(delta), 0) FROM daily WHERE account_id = $2 AND date > $3::date AND date <= $4::date
+		`, userID, acc.ID, lastClosing, today).Scan(&sinceClosing)
+		if err != nil {
+			return nil, err
+		}
+		if closingBalance := acc.Balance - sinceClosing; closingBalance < 0 {
+			acc.InitialOwed = -closingBalance
+		}
+	}
+
+	return accounts, nil
+}
+
+// loadFutureTransactions busca rendas, gastos e transferências com data entre amanhã e end
+func (h *ForecastHandler) loadFutureTransactions(ctx context.Context, userID int, today, end time.Time) ([]models.ForecastEvent, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date, account_id, COALESCE(account_amount, amount), 'income', description FROM incomes
+		WHERE user_id = $1 AND account_id IS NOT NULL AND date > $2 AND date <= $3 AND deleted_at IS NULL
+		UNION ALL
+		SELECT date, account_id, -COALESCE(account_amount, amount), 'expense', description FROM expenses
+		WHERE user_id = $1 AND account_id IS NOT NULL AND date > $2 AND date <= $3 AND deleted_at IS NULL
+		UNION ALL
+		SELECT date, to_account_id, COALESCE(to_amount, amount), 'transfer', COALESCE(description, '') FROM transfers
+		WHERE user_id = $1 AND to_account_id IS NOT NULL AND date > $2 AND date <= $3
+		UNION ALL
+		SELECT date, from_account_id, -amount, 'transfer', COALESCE(description, '') FROM transfers
+		WHERE user_id = $1 AND from_account_id IS NOT NULL AND date > $2 AND date <= $3
+	`, userID, today, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.ForecastEvent{}
+	for rows.Next() {
+		var ev models.ForecastEvent
+		if err := rows.Scan(&ev.Date, &ev.AccountID, &ev.Amount, &ev.Kind, &ev.Description); err != nil {
+			return nil, err
+		}
+		events = append(events, ev)
+	}
+	return events, rows.Err()
+}
+
+// loadRecurringCandidates busca rendas e gastos da janela de histórico até end,
+// incluindo lançamentos futuros para não projetar em dobro. Parcelas de empréstimos
+// ficam de fora: são projetadas pela tabela de amortização.
+func (h *ForecastHandler) loadRecurringCandidates(ctx context.Context, userID int, today, end time.Time) ([]forecastTransaction, error) {
+	historyStart := time.Date(today.Year(), today.Month()-recurringHistoryMons, 1, 0, 0, 0, 0, time.UTC)
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT 'income', description, account_id, COALESCE(account_amount, amount), date FROM incomes
+		WHERE user_id = $1 AND account_id IS NOT NULL AND date >= $2 AND date <= $3 AND deleted_at IS NULL
+		UNION ALL
+		SELECT 'expense', description, account_id, COALESCE(account_amount, amount), date FROM expenses
+		WHERE user_id = $1 AND account_id IS NOT NULL AND date >= $2 AND date <= $3 AND deleted_at IS NULL
+			AND id NOT IN (SELECT expense_id FROM loan_payments)
+	`, userID, historyStart, end)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	txs := []forecastTransaction{}
+	for rows.Next() {
+		var t forecastTransaction
+		if err := rows.Scan(&t.Kind, &t.Description, &t.AccountID, &t.Amount, &t.Date); err != nil {
+			return nil, err
+		}
+		txs = append(txs, t)
+	}
+	return txs, rows.Err()
+}
+
+// loadGoalSchedules busca as metas em andamento com aporte mensal programado
+func (h *ForecastHandler) loadGoalSchedules(ctx context.Context, userID int) ([]forecastGoalSchedule, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT g.name, g.contribution_amount, g.contribution_day, g.contribution_account_id, g.account_id,
+			g.target_amount - CASE WHEN a.id IS NOT NULL THEN a.balance * COALESCE(g.reserved_percent, 100) / 100 ELSE g.current_amount END
+		FROM goals g
+		LEFT JOIN accounts a ON a.id = g.account_id AND a.user_id = g.user_id AND a.deleted_at IS NULL
+		WHERE g.user_id = $1 AND g.completed_at IS NULL AND g.deleted_at IS NULL
+			AND g.contribution_amount IS NOT NULL AND g.contribution_day IS NOT NULL AND g.contribution_account_id IS NOT NULL
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	goals := []forecastGoalSchedule{}
+	for rows.Next() {
+		var g forecastGoalSchedule
+		if err := rows.Scan(&g.Name, &g.Amount, &g.Day, &g.FromID, &g.ToID, &g.Remaining); err != nil {
+			return nil, err
+		}
+		goals = append(goals, g)
+	}
+	return goals, rows.Err()
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+const (
+	goalStatusCompleted  = "completed"
+	goalStatusOnTrack    = "on_track"
+	goalStatusAtRisk     = "at_risk"
+	goalStatusNoDeadline = "no_deadline"
+
+	// Limite da simulação de conclusão (50 anos)
+	maxForecastMonths = 600
+	avgDaysPerMonth   = 30.44
+)
+
+// forecastGoal preenche MonthlyRequired, MonthlyPace, ProjectedCompletion e Status
+// usando o total aportado desde o primeiro aporte e o rendimento anual da meta.
+func forecastGoal(goal *models.Goal, contributed float64, firstContribution *time.Time, now time.Time) {
+	monthlyRate := 0.0
+	if goal.YieldRate != nil && *goal.YieldRate > 0 {
+		monthlyRate = math.Pow(1+*goal.YieldRate/100, 1.0/12) - 1
+	}
+
+	// Ritmo atual: média mensal de aportes desde o primeiro aporte
+	goal.MonthlyPace = 0
+	if firstContribution != nil && contributed > 0 {
+		elapsed := now.Sub(*firstContribution).Hours() / 24 / avgDaysPerMonth
+		if elapsed < 1 {
+			elapsed = 1
+		}
+		goal.MonthlyPace = roundMoney(contributed / elapsed)
+	}
+
+	if goal.CompletedAt != nil || (goal.TargetAmount > 0 && goal.CurrentAmount >= goal.TargetAmount) {
+		goal.Status = goalStatusCompleted
+		zero := 0.0
+		goal.MonthlyRequired = &zero
+		return
+	}
+
+	goal.ProjectedCompletion = projectCompletion(goal.CurrentAmount, goal.TargetAmount, goal.MonthlyPace, monthlyRate, now)
+
+	if goal.Deadline == nil {
+		goal.Status = goalStatusNoDeadline
+		return
+	}
+
+	months := math.Ceil(goal.Deadline.Sub(now).Hours() / 24 / avgDaysPerMonth)
+	if months < 1 {
+		months = 1
+	}
+	required := requiredMonthlyContribution(goal.CurrentAmount, goal.TargetAmount, monthlyRate, months)
+	goal.MonthlyRequired = &required
+
+	if goal.ProjectedCompletion != nil && !goal.ProjectedCompletion.After(*goal.Deadline) {
+		goal.Status = goalStatusOnTrack
+	} else {
+		goal.Status = goalStatusAtRisk
+	}
+}
+
+// requiredMonthlyContribution calcula o aporte mensal (PMT) que leva o saldo atual
+// ao alvo em n meses, considerando o rendimento mensal composto.
+func requiredMonthlyContribution(current, target, monthlyRate, months float64) float64 {
+	var pmt float64
+	if monthlyRate == 0 {
+		pmt = (target - current) / months
+	} else {
+		growth := math.Pow(1+monthlyRate, months)
+		pmt = (target - current*growth) * monthlyRate / (growth - 1)
+	}
+	if pmt < 0 {
+		pmt = 0
+	}
+	return roundMoney(pmt)
+}
+
+// projectCompletion simula mês a mês o saldo com o ritmo atual e retorna a data
+// em que o alvo é atingido, ou nil se não for atingido dentro do limite.
+func projectCompletion(current, target, monthlyPace, monthlyRate float64, now time.Time) *time.Time {
+	if monthlyPace <= 0 && (monthlyRate == 0 || current <= 0) {
+		return nil
+	}
+
+	balance := current
+	for m := 1; m <= maxForecastMonths; m++ {
+		balance = balance*(1+monthlyRate) + monthlyPace
+		if balance >= target {
+			date := now.AddDate(0, m, 0)
+			return &date
+		}
+	}
+	return nil
+}
+
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
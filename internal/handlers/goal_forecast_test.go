@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+func TestRequiredMonthlyContribution(t *testing.T) {
+	tests := []struct {
+		name        string
+		current     float64
+		target      float64
+		monthlyRate float64
+		months      float64
+		want        float64
+	}{
+		{name: "sem rendimento divide o que falta", current: 1000, target: 7000, monthlyRate: 0, months: 12, want: 500},
+		{name: "com rendimento precisa de menos", current: 0, target: 1200, monthlyRate: 0.01, months: 12, want: 94.62},
+		{name: "rendimento do saldo já cobre o alvo", current: 1000, target: 1100, monthlyRate: 0.01, months: 12, want: 0},
+		{name: "alvo já atingido", current: 8000, target: 7000, monthlyRate: 0, months: 6, want: 0},
+		{name: "prazo de um mês cobre tudo", current: 1000, target: 3000, monthlyRate: 0, months: 1, want: 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requiredMonthlyContribution(tt.current, tt.target, tt.monthlyRate, tt.months)
+			if got != tt.want {
+				t.Errorf("aporte mensal = %v, esperado %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProjectCompletion(t *testing.T) {
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		current     float64
+		target      float64
+		monthlyPace float64
+		monthlyRate float64
+		months      int // 0 = não atinge
+	}{
+		{name: "sem ritmo nem rendimento", current: 100, target: 1000},
+		{name: "só aportes, sem rendimento", current: 0, target: 1000, monthlyPace: 100, months: 10},
+		{name: "só rendimento", current: 1000, target: 1100, monthlyRate: 0.01, months: 10},
+		{name: "rendimento sem saldo não cresce", current: 0, target: 1000, monthlyRate: 0.01},
+		{name: "atinge exatamente no limite de 600 meses", current: 0, target: 6000, monthlyPace: 10, months: maxForecastMonths},
+		{name: "depois de 600 meses não projeta", current: 0, target: 6000.01, monthlyPace: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := projectCompletion(tt.current, tt.target, tt.monthlyPace, tt.monthlyRate, now)
+			if tt.months == 0 {
+				if got != nil {
+					t.Errorf("conclusão = %v, esperado nil", *got)
+				}
+				return
+			}
+			want := now.AddDate(0, tt.months, 0)
+			if got == nil || !got.Equal(want) {
+				t.Errorf("conclusão = %v, esperado %v", got, want)
+			}
+		})
+	}
+}
+
+func TestForecastGoal(t *testing.T) {
+	now := time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	past := now.AddDate(0, -2, 0)
+	future := now.AddDate(1, 0, 0)
+	firstContribution := now.AddDate(0, -4, 0)
+
+	t.Run("alvo atingido", func(t *testing.T) {
+		goal := models.Goal{TargetAmount: 1000, CurrentAmount: 1000, Deadline: &future}
+		forecastGoal(&goal, 1000, &firstContribution, now)
+		if goal.Status != goalStatusCompleted {
+			t.Errorf("status = %q, esperado %q", goal.Status, goalStatusCompleted)
+		}
+		if goal.MonthlyRequired == nil || *goal.MonthlyRequired != 0 {
+			t.Errorf("aporte mensal = %v, esperado 0", goal.MonthlyRequired)
+		}
+		if goal.ProjectedCompletion != nil {
+			t.Errorf("conclusão = %v, esperado nil", *goal.ProjectedCompletion)
+		}
+	})
+
+	t.Run("prazo vencido cobra o restante em um mês", func(t *testing.T) {
+		goal := models.Goal{TargetAmount: 3000, CurrentAmount: 1000, Deadline: &past}
+		forecastGoal(&goal, 1000, &firstContribution, now)
+		if goal.Status != goalStatusAtRisk {
+			t.Errorf("status = %q, esperado %q", goal.Status, goalStatusAtRisk)
+		}
+		if goal.MonthlyRequired == nil || *goal.MonthlyRequired != 2000 {
+			t.Errorf("aporte mensal = %v, esperado 2000", goal.MonthlyRequired)
+		}
+	})
+
+	t.Run("sem prazo", func(t *testing.T) {
+		goal := models.Goal{TargetAmount: 3000, CurrentAmount: 1000}
+		forecastGoal(&goal, 1000, &firstContribution, now)
+		if goal.Status != goalStatusNoDeadline {
+			t.Errorf("status = %q, esperado %q", goal.Status, goalStatusNoDeadline)
+		}
+		if goal.MonthlyRequired != nil {
+			t.Errorf("aporte mensal = %v, esperado nil", *goal.MonthlyRequired)
+		}
+	})
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
@@ -19,10 +22,17 @@ func (h *GoalHandler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 	userID, _ := userIDVal.(int)
 
 	var goalReq struct {
-		Name          string  `json:"name"`
-		TargetAmount  float64 `json:"target_amount"`
-		CurrentAmount float64 `json:"current_amount"`
-		Deadline      string  `json:"deadline"`
+		Name            string   `json:"name"`
+		TargetAmount    float64  `json:"target_amount"`
+		CurrentAmount   float64  `json:"current_amount"`
+		Deadline        string   `json:"deadline"`
+		YieldRate       *float64 `json:"yield_rate"`
+		AccountID       *int64   `json:"account_id"`
+		ReservedPercent *float64 `json:"reserved_percent"`
+
+		ContributionAmount    *float64 `json:"contribution_amount"`
+		ContributionDay       *int     `json:"contribution_day"`
+		ContributionAccountID *int64   `json:"contribution_account_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&goalReq); err != nil {
 		http.Error(w, "Dados inválidos", http.StatusBadRequest)
@@ -39,24 +49,70 @@ func (h *GoalHandler) CreateGoal(w http.ResponseWriter, r *http.Request) {
 		deadline = &parsedDate
 	}
 
+	if status, msg := h.validateGoalAccount(r.Context(), userID, goalReq.AccountID, goalReq.ReservedPercent); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if status, msg := h.validateContributionSchedule(r.Context(), userID, goalReq.ContributionAmount, goalReq.ContributionDay, goalReq.ContributionAccountID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
 	goal := models.Goal{
-		Name:          goalReq.Name,
-		TargetAmount:  goalReq.TargetAmount,
-		CurrentAmount: goalReq.CurrentAmount,
-		Deadline:      deadline,
+		Name:            goalReq.Name,
+		TargetAmount:    goalReq.TargetAmount,
+		CurrentAmount:   goalReq.CurrentAmount,
+		Deadline:        deadline,
+		YieldRate:       goalReq.YieldRate,
+		AccountID:       goalReq.AccountID,
+		ReservedPercent: goalReq.ReservedPercent,
+
+		ContributionAmount:    goalReq.ContributionAmount,
+		ContributionDay:       goalReq.ContributionDay,
+		ContributionAccountID: goalReq.ContributionAccountID,
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	query := `INSERT INTO goals (user_id, name, target_amount, current_amount, deadline) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
-	err := h.DB.QueryRow(query, userID, goal.Name, goal.TargetAmount, goal.CurrentAmount, goal.Deadline).Scan(&goal.ID, &goal.CreatedAt)
+	query := `INSERT INTO goals (user_id, name, target_amount, current_amount, deadline, yield_rate, account_id, reserved_percent, contribution_amount, contribution_day, contribution_account_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, created_at`
+	err = tx.QueryRowContext(r.Context(), query, userID, goal.Name, goal.TargetAmount, goal.CurrentAmount, goal.Deadline, goal.YieldRate, goal.AccountID, goal.ReservedPercent,
+		goal.ContributionAmount, goal.ContributionDay, goal.ContributionAccountID).Scan(&goal.ID, &goal.CreatedAt)
 	if err != nil {
 		http.Error(w, "Erro ao criar meta", http.StatusInternalServerError)
 		return
 	}
+	if err := recordAudit(tx, r, userID, "goal", goal.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	// Meta lastreada em conta: o valor atual vem do saldo da conta
+	if goal.AccountID != nil {
+		err = h.DB.QueryRowContext(r.Context(), `
+			SELECT balance * COALESCE($1, 100) / 100 FROM accounts WHERE id = $2 AND user_id = $3
+		`, goal.ReservedPercent, goal.AccountID, userID).Scan(&goal.CurrentAmount)
+		if err != nil {
+			http.Error(w, "Erro ao buscar saldo da conta da meta", http.StatusInternalServerError)
+			return
+		}
+	}
 
 	goal.UserID = userID
 	if goal.TargetAmount > 0 {
 		goal.Progress = (goal.CurrentAmount / goal.TargetAmount) * 100
 	}
+	forecastGoal(&goal, 0, nil, time.Now())
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(goal)
@@ -66,22 +122,52 @@ func (h *GoalHandler) GetGoals(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	rows, err := h.DB.Query(`
-		SELECT id, name, target_amount, current_amount, deadline, created_at, completed_at 
-		FROM goals 
-		WHERE user_id = $1 
-		ORDER BY completed_at NULLS FIRST, created_at DESC
-	`, userID)
+	// Em /v1/goals/{id} retorna só a meta
+	filter := ""
+	args := []interface{}{userID}
+	single := r.PathValue("id")
+	if single != "" {
+		singleID, err := strconv.ParseInt(single, 10, 64)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		filter = " AND g.id = $2"
+		args = append(args, singleID)
+	}
+
+	// Aportes agregados por meta para calcular o ritmo atual
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT g.id, g.name, g.target_amount,
+			CASE WHEN a.id IS NOT NULL THEN a.balance * COALESCE(g.reserved_percent, 100) / 100 ELSE g.current_amount END,
+			g.deadline, g.created_at, g.completed_at, g.yield_rate, g.account_id, g.reserved_percent,
+			g.contribution_amount, g.contribution_day, g.contribution_account_id,
+			COALESCE(c.total, 0), c.first_date
+		FROM goals g
+		LEFT JOIN accounts a ON a.id = g.account_id AND a.user_id = g.user_id AND a.deleted_at IS NULL
+		LEFT JOIN (
+			SELECT goal_id, SUM(amount) AS total, MIN(date) AS first_date
+			FROM goal_contributions
+			WHERE user_id = $1
+			GROUP BY goal_id
+		) c ON c.goal_id = g.id
+		WHERE g.user_id = $1 AND g.deleted_at IS NULL`+filter+`
+		ORDER BY g.completed_at NULLS FIRST, g.created_at DESC
+	`, args...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar metas", http.StatusInternalServerError)
 		return
 	}
 	defer rows.Close()
 
+	now := time.Now()
 	goals := []models.Goal{}
 	for rows.Next() {
 		var goal models.Goal
-		if err := rows.Scan(&goal.ID, &goal.Name, &goal.TargetAmount, &goal.CurrentAmount, &goal.Deadline, &goal.CreatedAt, &goal.CompletedAt); err != nil {
+		var contributed float64
+		var firstContribution *time.Time
+		if err := rows.Scan(&goal.ID, &goal.Name, &goal.TargetAmount, &goal.CurrentAmount, &goal.Deadline, &goal.CreatedAt, &goal.CompletedAt, &goal.YieldRate, &goal.AccountID, &goal.ReservedPercent,
+			&goal.ContributionAmount, &goal.ContributionDay, &goal.ContributionAccountID, &contributed, &firstContribution); err != nil {
 			http.Error(w, "Erro ao ler metas", http.StatusInternalServerError)
 			return
 		}
@@ -92,17 +178,26 @@ func (h *GoalHandler) GetGoals(w http.ResponseWriter, r *http.Request) {
 				goal.Progress = 100
 			}
 		}
+		forecastGoal(&goal, contributed, firstContribution, now)
 		goals = append(goals, goal)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
+	if single != "" {
+		if len(goals) == 0 {
+			http.Error(w, "Meta não encontrada", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(goals[0])
+		return
+	}
 	json.NewEncoder(w).Encode(goals)
 }
 
 func (h *GoalHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
@@ -110,10 +205,17 @@ func (h *GoalHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var goalReq struct {
-		Name          string  `json:"name"`
-		TargetAmount  float64 `json:"target_amount"`
-		CurrentAmount float64 `json:"current_amount"`
-		Deadline      string  `json:"deadline"`
+		Name            string   `json:"name"`
+		TargetAmount    float64  `json:"target_amount"`
+		CurrentAmount   float64  `json:"current_amount"`
+		Deadline        string   `json:"deadline"`
+		YieldRate       *float64 `json:"yield_rate"`
+		AccountID       *int64   `json:"account_id"`
+		ReservedPercent *float64 `json:"reserved_percent"`
+
+		ContributionAmount    *float64 `json:"contribution_amount"`
+		ContributionDay       *int     `json:"contribution_day"`
+		ContributionAccountID *int64   `json:"contribution_account_id"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&goalReq); err != nil {
 		http.Error(w, "Dados inválidos", http.StatusBadRequest)
@@ -130,32 +232,77 @@ func (h *GoalHandler) UpdateGoal(w http.ResponseWriter, r *http.Request) {
 		deadline = &parsedDate
 	}
 
-	// Mark as completed if reached target
+	if status, msg := h.validateGoalAccount(r.Context(), userID, goalReq.AccountID, goalReq.ReservedPercent); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if status, msg := h.validateContributionSchedule(r.Context(), userID, goalReq.ContributionAmount, goalReq.ContributionDay, goalReq.ContributionAccountID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	if status, msg := checkPatchBase(r.Context(), tx, id, userID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "goal", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta", http.StatusInternalServerError)
+		return
+	}
+
+	// Mark as completed if reached target (linked goals derive progress from the account)
 	var completedAt *time.Time
-	if goalReq.CurrentAmount >= goalReq.TargetAmount {
+	if goalReq.AccountID == nil && goalReq.CurrentAmount >= goalReq.TargetAmount {
 		now := time.Now()
 		completedAt = &now
 	}
 
-	_, err := h.DB.Exec(`
+	_, err = tx.ExecContext(r.Context(), `
 		UPDATE goals 
-		SET name = $1, target_amount = $2, current_amount = $3, deadline = $4, completed_at = $5
-		WHERE id = $6 AND user_id = $7
-	`, goalReq.Name, goalReq.TargetAmount, goalReq.CurrentAmount, deadline, completedAt, id, userID)
+		SET name = $1, target_amount = $2, current_amount = $3, deadline = $4, completed_at = $5, yield_rate = $6,
+			account_id = $7, reserved_percent = $8,
+			contribution_amount = $9, contribution_day = $10, contribution_account_id = $11
+		WHERE id = $12 AND user_id = $13 AND deleted_at IS NULL
+	`, goalReq.Name, goalReq.TargetAmount, goalReq.CurrentAmount, deadline, completedAt, goalReq.YieldRate,
+		goalReq.AccountID, goalReq.ReservedPercent,
+		goalReq.ContributionAmount, goalReq.ContributionDay, goalReq.ContributionAccountID, id, userID)
 
 	if err != nil {
 		http.Error(w, "Erro ao atualizar meta", http.StatusInternalServerError)
 		return
 	}
+	if err := recordAudit(tx, r, userID, "goal", id, auditUpdate, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"message": "Meta atualizada com sucesso"}`))
 }
 
+// PatchGoal altera só os campos enviados da meta (PATCH /v1/goals/{id})
+func (h *GoalHandler) PatchGoal(w http.ResponseWriter, r *http.Request) {
+	mergePatch(w, r, h.DB, goalPatchSQL, "Meta não encontrada", h.UpdateGoal)
+}
+
 func (h *GoalHandler) AddMoneyToGoal(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
@@ -165,14 +312,25 @@ func (h *GoalHandler) AddMoneyToGoal(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Amount    float64 `json:"amount"`
 		AccountID int64   `json:"account_id"`
+		Date      string  `json:"date"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Dados inválidos", http.StatusBadRequest)
 		return
 	}
 
+	contributionDate := time.Now().UTC()
+	if req.Date != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			http.Error(w, "Formato de data inválido", http.StatusBadRequest)
+			return
+		}
+		contributionDate = parsed
+	}
+
 	// Start transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
 		return
@@ -181,7 +339,91 @@ func (h *GoalHandler) AddMoneyToGoal(w http.ResponseWriter, r *http.Request) {
 
 	// Get current goal data
 	var currentAmount, targetAmount float64
-	err = tx.QueryRow(`SELECT current_amount, target_amount FROM goals WHERE id = $1 AND user_id = $2`, id, userID).Scan(&currentAmount, &targetAmount)
+	var goalAccountID *int64
+	err = tx.QueryRowContext(r.Context(), `SELECT current_amount, target_amount, account_id FROM goals WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID).Scan(&currentAmount, &targetAmount, &goalAccountID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta", http.StatusInternalServerError)
+		return
+	}
+
+	// Meta lastreada em conta: o aporte vira uma transferência para a conta da meta
+	if goalAccountID != nil {
+		if req.AccountID == 0 || req.AccountID == *goalAccountID {
+			http.Error(w, "Escolha uma conta de origem diferente da conta da meta", http.StatusBadRequest)
+			return
+		}
+		if req.Amount <= 0 {
+			http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+			return
+		}
+
+		// NULL quando a conta de origem não existe, foi excluída ou é de outro usuário
+		var sameCurrency sql.NullBool
+		err = tx.QueryRowContext(r.Context(), `
+			SELECT (SELECT currency FROM accounts WHERE id = $1 AND user_id = $3 AND deleted_at IS NULL) = (SELECT currency FROM accounts WHERE id = $2 AND user_id = $3)
+		`, req.AccountID, *goalAccountID, userID).Scan(&sameCurrency)
+		if err != nil {
+			http.Error(w, "Erro ao validar contas", http.StatusInternalServerError)
+			return
+		}
+		if !sameCurrency.Valid {
+			http.Error(w, "Conta inválida", http.StatusForbidden)
+			return
+		}
+		if !sameCurrency.Bool {
+			http.Error(w, "Contas em moedas diferentes: use /accounts/transfer com to_amount", http.StatusBadRequest)
+			return
+		}
+
+		var transferID int64
+		err = tx.QueryRowContext(r.Context(), `
+			INSERT INTO transfers (user_id, from_account_id, to_account_id, amount, description, date)
+			SELECT $1, $2, $3, $4, 'Aporte na meta ' || name, $5 FROM goals WHERE id = $6 AND user_id = $1
+			RETURNING id
+		`, userID, req.AccountID, *goalAccountID, req.Amount, contributionDate, id).Scan(&transferID)
+		if err != nil {
+			http.Error(w, "Erro ao registrar transferência", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, req.Amount, req.AccountID, userID)
+		if err != nil {
+			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
+			return
+		}
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, req.Amount, *goalAccountID, userID)
+		if err != nil {
+			http.Error(w, "Erro ao atualizar saldo da conta da meta", http.StatusInternalServerError)
+			return
+		}
+
+		if err = recordTransferContributions(r.Context(), tx, userID, transferID, req.AccountID, *goalAccountID, req.Amount, contributionDate); err != nil {
+			http.Error(w, "Erro ao registrar aporte", http.StatusInternalServerError)
+			return
+		}
+
+		// Aporte retroativo muda o patrimônio dos dias seguintes
+		if err = staleNetWorthFrom(r.Context(), tx, userID, contributionDate); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+			http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+			return
+		}
+		if err = recordAudit(tx, r, userID, "transfer", transferID, auditCreate, nil); err != nil {
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Valor adicionado com sucesso"}`))
+		return
+	}
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "goal", id)
 	if err != nil {
 		http.Error(w, "Erro ao buscar meta", http.StatusInternalServerError)
 		return
@@ -195,19 +437,47 @@ func (h *GoalHandler) AddMoneyToGoal(w http.ResponseWriter, r *http.Request) {
 		completedAt = &now
 	}
 
-	_, err = tx.Exec(`UPDATE goals SET current_amount = $1, completed_at = $2 WHERE id = $3 AND user_id = $4`, newCurrentAmount, completedAt, id, userID)
+	_, err = tx.ExecContext(r.Context(), `UPDATE goals SET current_amount = $1, completed_at = $2 WHERE id = $3 AND user_id = $4`, newCurrentAmount, completedAt, id, userID)
 	if err != nil {
 		http.Error(w, "Erro ao atualizar meta", http.StatusInternalServerError)
 		return
 	}
 
+	// Record contribution history for forecasting
+	var accountID *int64
+	if req.AccountID != 0 {
+		accountID = &req.AccountID
+	}
+	var contributionID int64
+	err = tx.QueryRowContext(r.Context(), `INSERT INTO goal_contributions (goal_id, user_id, account_id, amount, date) VALUES ($1, $2, $3, $4, $5) RETURNING id`, id, userID, accountID, req.Amount, contributionDate).Scan(&contributionID)
+	if err != nil {
+		http.Error(w, "Erro ao registrar aporte", http.StatusInternalServerError)
+		return
+	}
+
 	// Deduct from account balance
-	_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, req.Amount, req.AccountID, userID)
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, req.Amount, req.AccountID, userID)
 	if err != nil {
 		http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
 		return
 	}
 
+	// Aporte retroativo muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, contributionDate); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		return
+	}
+
+	if err = recordAudit(tx, r, userID, "goal", id, auditUpdate, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err = recordAudit(tx, r, userID, "goal_contribution", contributionID, auditCreate, nil); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
@@ -218,21 +488,145 @@ func (h *GoalHandler) AddMoneyToGoal(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"message": "Valor adicionado com sucesso"}`))
 }
 
+// GetGoalContributions lista o histórico de aportes de uma meta
+func (h *GoalHandler) GetGoalContributions(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, goal_id, account_id, transfer_id, amount, date, created_at
+		FROM goal_contributions
+		WHERE goal_id = $1 AND user_id = $2
+		ORDER BY date DESC, id DESC
+	`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar aportes", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	contributions := []models.GoalContribution{}
+	for rows.Next() {
+		var c models.GoalContribution
+		if err := rows.Scan(&c.ID, &c.GoalID, &c.AccountID, &c.TransferID, &c.Amount, &c.Date, &c.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler aportes", http.StatusInternalServerError)
+			return
+		}
+		contributions = append(contributions, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contributions)
+}
+
 func (h *GoalHandler) DeleteGoal(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
 		return
 	}
 
-	_, err := h.DB.Exec(`DELETE FROM goals WHERE id = $1 AND user_id = $2`, id, userID)
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "goal", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar meta", http.StatusInternalServerError)
+		return
+	}
+
+	// Vai para a lixeira; anexos e aportes ficam até a limpeza definitiva
+	result, err := tx.ExecContext(r.Context(), `UPDATE goals SET deleted_at = NOW() WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, id, userID)
 	if err != nil {
 		http.Error(w, "Erro ao deletar meta", http.StatusInternalServerError)
 		return
 	}
 
+	if trashed, _ := result.RowsAffected(); trashed > 0 {
+		if err = recordAudit(tx, r, userID, "goal", id, auditDelete, before); err != nil {
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// validateGoalAccount garante que a conta vinculada pertence ao usuário e que o
+// percentual reservado é válido. Retorna status 0 quando está tudo certo.
+func (h *GoalHandler) validateGoalAccount(ctx context.Context, userID int, accountID *int64, reservedPercent *float64) (int, string) {
+	if reservedPercent != nil && (*reservedPercent <= 0 || *reservedPercent > 100) {
+		return http.StatusBadRequest, "Percentual reservado deve estar entre 0 e 100"
+	}
+	if accountID == nil {
+		if reservedPercent != nil {
+			return http.StatusBadRequest, "Percentual reservado exige uma conta vinculada"
+		}
+		return 0, ""
+	}
+
+	var count int
+	if err := h.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, *accountID, userID).Scan(&count); err != nil {
+		return http.StatusInternalServerError, "Erro ao validar conta"
+	}
+	if count == 0 {
+		return http.StatusForbidden, "Conta inválida"
+	}
+	return 0, ""
+}
+
+// validateContributionSchedule valida o aporte mensal programado: valor, dia do
+// mês e conta de origem devem ser informados juntos. Retorna status 0 quando ok.
+func (h *GoalHandler) validateContributionSchedule(ctx context.Context, userID int, amount *float64, day *int, accountID *int64) (int, string) {
+	if amount == nil && day == nil && accountID == nil {
+		return 0, ""
+	}
+	if amount == nil || day == nil || accountID == nil {
+		return http.StatusBadRequest, "Aporte programado exige valor, dia e conta de origem"
+	}
+	if *amount <= 0 {
+		return http.StatusBadRequest, "Valor do aporte programado deve ser maior que zero"
+	}
+	if *day < 1 || *day > 31 {
+		return http.StatusBadRequest, "Dia do aporte programado deve estar entre 1 e 31"
+	}
+
+	var count int
+	if err := h.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, *accountID, userID).Scan(&count); err != nil {
+		return http.StatusInternalServerError, "Erro ao validar conta"
+	}
+	if count == 0 {
+		return http.StatusForbidden, "Conta inválida"
+	}
+	return 0, ""
+}
+
+// recordTransferContributions registra como aporte a entrada de uma transferência
+// em contas vinculadas a metas, respeitando o percentual reservado de cada meta.
+func recordTransferContributions(ctx context.Context, tx *sql.Tx, userID int, transferID, fromAccountID, toAccountID int64, amount float64, date time.Time) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO goal_contributions (goal_id, user_id, account_id, transfer_id, amount, date)
+		SELECT id, user_id, $2, $3, ROUND($4 * COALESCE(reserved_percent, 100) / 100, 2), $5
+		FROM goals
+		WHERE user_id = $1 AND account_id = $6 AND completed_at IS NULL AND deleted_at IS NULL
+	`, userID, fromAccountID, transferID, amount, date, toAccountID)
+	return err
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/database"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthHandler struct {
+	DB *sql.DB
+}
+
+// Healthz indica só que o processo está no ar (liveness); não depende do banco
+func (h *HealthHandler) Healthz(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// Readyz indica se o servidor pode receber tráfego: banco respondendo (ping com timeout)
+// e migrations aplicadas até database.SchemaVersion. Responde 503 enquanto não estiver pronto.
+func (h *HealthHandler) Readyz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "migrations": "ok"}
+	ready := true
+
+	if err := h.DB.PingContext(ctx); err != nil {
+		slog.WarnContext(r.Context(), "Readiness: banco inacessível", "error", err)
+		checks["database"] = "Banco inacessível"
+		checks["migrations"] = "Não verificado"
+		ready = false
+	} else if version, err := database.AppliedSchemaVersion(ctx, h.DB); err != nil {
+		slog.WarnContext(r.Context(), "Readiness: erro ao verificar migrations", "error", err)
+		checks["migrations"] = "Erro ao verificar migrations"
+		ready = false
+	} else if version < database.SchemaVersion {
+		checks["migrations"] = fmt.Sprintf("Migrations pendentes: banco na versão %d, esperada %d", version, database.SchemaVersion)
+		ready = false
+	}
+
+	status := "ok"
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		status = "unavailable"
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": status,
+		"checks": checks,
+	})
+}
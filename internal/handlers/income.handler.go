@@ -3,111 +3,160 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
 	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
 )
 
 type IncomeHandler struct {
 	DB *sql.DB
 }
 
-func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Description string  `json:"description"`
-		Amount      float64 `json:"amount"`
-		Date        string  `json:"date"`
-		AccountID   *int64  `json:"account_id"`
-	}
+// incomeRequest é o corpo aceito na criação e na atualização de rendas
+type incomeRequest struct {
+	Description string   `json:"description"`
+	Amount      float64  `json:"amount"`
+	Date        string   `json:"date"`
+	AccountID   *int64   `json:"account_id"`
+	Tags        []string `json:"tags"`
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Erro ao ler o corpo da requisição", http.StatusBadRequest)
-		return
-	}
+	Currency      string   `json:"currency"`       // padrão: moeda da conta
+	AccountAmount *float64 `json:"account_amount"` // valor na moeda da conta, quando difere
+}
 
+// newIncome monta a renda a partir do corpo. Retorna status 0 quando está tudo certo.
+func newIncome(req incomeRequest) (models.Income, int, string) {
 	incomeDate := time.Now().UTC()
 	if strings.TrimSpace(req.Date) != "" {
 		parsed, err := time.Parse("2006-01-02", req.Date)
 		if err != nil {
-			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
-			return
+			return models.Income{}, http.StatusBadRequest, "Data inválida, use YYYY-MM-DD"
 		}
 		incomeDate = parsed
 	}
 
-	income := models.Income{
+	return models.Income{
 		Description: req.Description,
 		Amount:      req.Amount,
 		Date:        incomeDate,
 		Month:       int(incomeDate.Month()),
 		Year:        incomeDate.Year(),
 		AccountID:   req.AccountID,
+		Tags:        normalizeTags(req.Tags),
+	}, 0, ""
+}
+
+func (h *IncomeHandler) CreateIncome(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req incomeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Erro ao ler o corpo da requisição", http.StatusBadRequest)
+		return
 	}
 
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
+	// Start transaction
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	income, status, msg := h.createIncome(tx, r, userID, req)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	metrics.TransactionsCreated.WithLabelValues("income").Inc()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(income)
+}
+
+// createIncome grava a renda e credita a conta dentro de tx (também usado pelo lote).
+// Retorna status 0 quando está tudo certo.
+func (h *IncomeHandler) createIncome(tx *sql.Tx, r *http.Request, userID int, req incomeRequest) (models.Income, int, string) {
+	income, status, msg := newIncome(req)
+	if status != 0 {
+		return income, status, msg
+	}
+
 	// Se não tem account_id, cria/busca Carteira Geral
 	if income.AccountID == nil {
 		accountHandler := &AccountHandler{DB: h.DB}
-		defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(userID)
+		defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(r.Context(), userID)
 		if err != nil {
-			http.Error(w, "Erro ao criar conta padrão", http.StatusInternalServerError)
-			fmt.Println("Erro ao criar conta padrão:", err)
-			return
+			slog.ErrorContext(r.Context(), "Erro ao criar conta padrão", "error", err)
+			return income, http.StatusInternalServerError, "Erro ao criar conta padrão"
 		}
 		income.AccountID = &defaultAccountID
 	}
 
-	// Start transaction
-	tx, err := h.DB.Begin()
-	if err != nil {
-		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
-		return
+	income.Currency = req.Currency
+	accountAmount, status, msg := resolveTransactionCurrency(r.Context(), tx, userID, *income.AccountID, &income.Currency, income.Amount, req.AccountAmount, income.Date)
+	if status != 0 {
+		return income, status, msg
 	}
-	defer tx.Rollback()
+	income.AccountAmount = accountAmount
 
 	query := `
-		INSERT INTO incomes (description, amount, date, month, year, user_id, account_id)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO incomes (description, amount, date, month, year, user_id, account_id, currency, account_amount)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		RETURNING id;
 	`
 
-	err = tx.QueryRow(query, income.Description, income.Amount, income.Date, income.Month, income.Year, userID, income.AccountID).Scan(&income.ID)
+	err := tx.QueryRowContext(r.Context(), query, income.Description, income.Amount, income.Date, income.Month, income.Year, userID, income.AccountID, income.Currency, income.AccountAmount).Scan(&income.ID)
 	if err != nil {
-		http.Error(w, "Erro ao inserir renda", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao inserir renda", "error", err)
+		return income, http.StatusInternalServerError, "Erro ao inserir renda"
 	}
 
 	// Update account balance if account_id is provided
 	if income.AccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, income.Amount, income.AccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, accountEffect(income.Amount, income.AccountAmount), income.AccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
-			fmt.Println("Erro:", err)
-			return
+			slog.ErrorContext(r.Context(), "Erro ao atualizar saldo da conta", "error", err)
+			return income, http.StatusInternalServerError, "Erro ao atualizar saldo da conta"
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, income.Date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return income, http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(income)
+	if err = setTransactionTags(r.Context(), tx, userID, "income", int64(income.ID), income.Tags); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao salvar tags", "error", err)
+		return income, http.StatusInternalServerError, "Erro ao salvar tags"
+	}
+
+	if err = recordAudit(tx, r, userID, "income", income.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return income, http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return income, 0, ""
 }
 
 func (h *IncomeHandler) GetIncomes(w http.ResponseWriter, r *http.Request) {
@@ -121,7 +170,7 @@ func (h *IncomeHandler) GetIncomes(w http.ResponseWriter, r *http.Request) {
 	monthParam := r.URL.Query().Get("month")
 	yearParam := r.URL.Query().Get("year")
 
-	baseQuery := `SELECT id, description, amount, date, month, year, account_id FROM incomes WHERE user_id = $1`
+	baseQuery := `SELECT id, description, amount, date, month, year, account_id, currency, account_amount FROM incomes WHERE user_id = $1 AND deleted_at IS NULL`
 	args := []interface{}{userID}
 
 	if monthParam != "" {
@@ -136,12 +185,36 @@ func (h *IncomeHandler) GetIncomes(w http.ResponseWriter, r *http.Request) {
 		args = append(args, yearVal)
 	}
 
-	baseQuery += " ORDER BY date DESC"
+	if tags := parseTagFilter(r); tags != nil {
+		baseQuery += tagFilterClause("income", "id", len(args)+1)
+		args = append(args, pq.Array(tags))
+	}
 
-	rows, err := h.DB.Query(baseQuery, args...)
+	// Em /v1/incomes/{id} retorna só a renda
+	single := r.PathValue("id")
+	if single != "" {
+		singleID, err := strconv.ParseInt(single, 10, 64)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, singleID)
+	}
+
+	page, err := parsePageParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var pageClause string
+	pageClause, args = page.keysetClause(args)
+	baseQuery += pageClause
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
 	if err != nil {
 		http.Error(w, "Erro ao buscar rendas", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
+		slog.ErrorContext(r.Context(), "Erro ao buscar rendas", "error", err)
 		return
 	}
 	defer rows.Close()
@@ -149,14 +222,58 @@ func (h *IncomeHandler) GetIncomes(w http.ResponseWriter, r *http.Request) {
 	var incomes []models.Income
 	for rows.Next() {
 		var income models.Income
-		if err := rows.Scan(&income.ID, &income.Description, &income.Amount, &income.Date, &income.Month, &income.Year, &income.AccountID); err != nil {
+		if err := rows.Scan(&income.ID, &income.Description, &income.Amount, &income.Date, &income.Month, &income.Year, &income.AccountID, &income.Currency, &income.AccountAmount); err != nil {
 			http.Error(w, "Erro ao ler rendas", http.StatusInternalServerError)
 			return
 		}
 		incomes = append(incomes, income)
 	}
 
+	// Uma linha além do limite indica que existe próxima página
+	var nextCursor *string
+	if page.Enabled && len(incomes) > page.Limit {
+		incomes = incomes[:page.Limit]
+		last := incomes[len(incomes)-1]
+		c := encodeCursor(pageCursor{Date: last.Date, ID: int64(last.ID)})
+		nextCursor = &c
+	}
+
+	ids := make([]int64, len(incomes))
+	for i, inc := range incomes {
+		ids[i] = int64(inc.ID)
+	}
+	tags, err := loadTransactionTags(r.Context(), h.DB, userID, "income", ids)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tags", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar tags", "error", err)
+		return
+	}
+	for i := range incomes {
+		incomes[i].Tags = tags[int64(incomes[i].ID)]
+		if incomes[i].Tags == nil {
+			incomes[i].Tags = []string{}
+		}
+	}
+
+	if single != "" {
+		if len(incomes) == 0 {
+			http.Error(w, "Renda não encontrada", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(incomes[0])
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
+	if page.Enabled {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"items":       incomes,
+			"next_cursor": nextCursor,
+			"limit":       page.Limit,
+		})
+		return
+	}
 	json.NewEncoder(w).Encode(incomes)
 }
 
@@ -166,99 +283,146 @@ func (h *IncomeHandler) UpdateIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
 		return
 	}
+	incomeID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
 
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	var req struct {
-		Description string  `json:"description"`
-		Amount      float64 `json:"amount"`
-		Date        string  `json:"date"`
-		AccountID   *int64  `json:"account_id"`
-	}
-
+	var req incomeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
 		return
 	}
 
-	incomeDate := time.Now().UTC()
-	if strings.TrimSpace(req.Date) != "" {
-		parsed, err := time.Parse("2006-01-02", req.Date)
-		if err != nil {
-			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
-			return
-		}
-		incomeDate = parsed
+	// Start transaction
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
 	}
+	defer tx.Rollback()
 
-	income := models.Income{
-		Description: req.Description,
-		Amount:      req.Amount,
-		Date:        incomeDate,
-		Month:       int(incomeDate.Month()),
-		Year:        incomeDate.Year(),
-		AccountID:   req.AccountID,
+	if status, msg := checkPatchBase(r.Context(), tx, id, userID); status != 0 {
+		http.Error(w, msg, status)
+		return
 	}
 
-	// Start transaction
-	tx, err := h.DB.Begin()
+	if status, msg := h.updateIncome(tx, r, userID, incomeID, req); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	err = tx.Commit()
 	if err != nil {
-		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
 		return
 	}
-	defer tx.Rollback()
 
-	// Get old income data
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Renda atualizada com sucesso"}`))
+}
+
+// updateIncome altera a renda dentro de tx, movendo o efeito no saldo da conta
+// antiga para a nova. Retorna status 0 quando está tudo certo.
+func (h *IncomeHandler) updateIncome(tx *sql.Tx, r *http.Request, userID int, incomeID int64, req incomeRequest) (int, string) {
+	income, status, msg := newIncome(req)
+	if status != 0 {
+		return status, msg
+	}
+
+	// Get old income data (amount already in the account currency)
 	var oldAmount float64
 	var oldAccountID *int64
-	err = tx.QueryRow(`SELECT amount, account_id FROM incomes WHERE id = $1 AND user_id = $2`, id, userID).Scan(&oldAmount, &oldAccountID)
+	var oldCurrency string
+	var oldDate time.Time
+	err := tx.QueryRowContext(r.Context(), `SELECT COALESCE(account_amount, amount), account_id, currency, date FROM incomes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, incomeID, userID).Scan(&oldAmount, &oldAccountID, &oldCurrency, &oldDate)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Renda não encontrada"
+	}
 	if err != nil {
-		http.Error(w, "Erro ao buscar renda", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar renda"
+	}
+	before, err := auditSnapshot(r.Context(), tx, userID, "income", incomeID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar renda"
+	}
+
+	// Sem o campo currency mantém a moeda atual da renda
+	income.Currency = req.Currency
+	if strings.TrimSpace(income.Currency) == "" {
+		income.Currency = oldCurrency
+	}
+	if income.AccountID != nil {
+		accountAmount, status, msg := resolveTransactionCurrency(r.Context(), tx, userID, *income.AccountID, &income.Currency, income.Amount, req.AccountAmount, income.Date)
+		if status != 0 {
+			return status, msg
+		}
+		income.AccountAmount = accountAmount
+	} else if code, ok := normalizeCurrency(income.Currency); ok {
+		income.Currency = code
+	} else {
+		return http.StatusBadRequest, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)"
 	}
 
 	// Update income
-	query := `UPDATE incomes SET description = $1, amount = $2, date = $3, month = $4, year = $5, account_id = $6 WHERE id = $7 AND user_id = $8`
-	_, err = tx.Exec(query, income.Description, income.Amount, income.Date, income.Month, income.Year, income.AccountID, id, userID)
+	query := `UPDATE incomes SET description = $1, amount = $2, date = $3, month = $4, year = $5, account_id = $6, currency = $7, account_amount = $8 WHERE id = $9 AND user_id = $10`
+	_, err = tx.ExecContext(r.Context(), query, income.Description, income.Amount, income.Date, income.Month, income.Year, income.AccountID, income.Currency, income.AccountAmount, incomeID, userID)
 	if err != nil {
-		http.Error(w, "Erro ao atualizar renda", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
-		return
+		slog.ErrorContext(r.Context(), "Erro ao atualizar renda", "error", err)
+		return http.StatusInternalServerError, "Erro ao atualizar renda"
 	}
 
 	// Adjust account balances
 	// Restore old account balance
 	if oldAccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, oldAmount, oldAccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, oldAmount, oldAccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta antiga", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da conta antiga"
 		}
 	}
 
 	// Add to new account balance
 	if income.AccountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, income.Amount, income.AccountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, accountEffect(income.Amount, income.AccountAmount), income.AccountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da nova conta", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da nova conta"
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, oldDate, income.Date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(`{"message": "Renda atualizada com sucesso"}`))
+	// Tags are only replaced when the field is sent
+	if req.Tags != nil {
+		if err = setTransactionTags(r.Context(), tx, userID, "income", incomeID, req.Tags); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao salvar tags", "error", err)
+			return http.StatusInternalServerError, "Erro ao salvar tags"
+		}
+	}
+
+	if err = recordAudit(tx, r, userID, "income", incomeID, auditUpdate, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return 0, ""
+}
+
+// PatchIncome altera só os campos enviados da renda (PATCH /v1/incomes/{id})
+func (h *IncomeHandler) PatchIncome(w http.ResponseWriter, r *http.Request) {
+	mergePatch(w, r, h.DB, incomePatchSQL, "Renda não encontrada", h.UpdateIncome)
 }
 
 func (h *IncomeHandler) DeleteIncome(w http.ResponseWriter, r *http.Request) {
@@ -267,53 +431,85 @@ func (h *IncomeHandler) DeleteIncome(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := r.URL.Query().Get("id")
+	id := pathParam(r, "id")
 	if id == "" {
 		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
 		return
 	}
+	incomeID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
 
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
 	// Start transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
 		return
 	}
 	defer tx.Rollback()
 
+	if status, msg := h.deleteIncome(tx, r, userID, incomeID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// deleteIncome move a renda para a lixeira dentro de tx, estornando o saldo da conta.
+// Retorna status 0 quando está tudo certo.
+func (h *IncomeHandler) deleteIncome(tx *sql.Tx, r *http.Request, userID int, incomeID int64) (int, string) {
 	// Get income data before deleting to restore account balance
 	var amount float64
 	var accountID *int64
-	err = tx.QueryRow(`SELECT amount, account_id FROM incomes WHERE id = $1 AND user_id = $2`, id, userID).Scan(&amount, &accountID)
+	var date time.Time
+	err := tx.QueryRowContext(r.Context(), `SELECT COALESCE(account_amount, amount), account_id, date FROM incomes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, incomeID, userID).Scan(&amount, &accountID, &date)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Renda não encontrada"
+	}
 	if err != nil {
-		http.Error(w, "Erro ao buscar renda", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao buscar renda"
+	}
+	before, err := auditSnapshot(r.Context(), tx, userID, "income", incomeID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar renda"
 	}
 
-	// Delete income
-	_, err = tx.Exec(`DELETE FROM incomes WHERE id = $1 AND user_id = $2`, id, userID)
+	// Move to trash (anexos ficam até a limpeza definitiva)
+	_, err = tx.ExecContext(r.Context(), `UPDATE incomes SET deleted_at = NOW() WHERE id = $1 AND user_id = $2`, incomeID, userID)
 	if err != nil {
-		http.Error(w, "Erro ao deletar renda", http.StatusInternalServerError)
-		return
+		return http.StatusInternalServerError, "Erro ao deletar renda"
 	}
 
 	// Restore account balance if account_id exists
 	if accountID != nil {
-		_, err = tx.Exec(`UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, amount, accountID, userID)
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, amount, accountID, userID)
 		if err != nil {
-			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
-			return
+			return http.StatusInternalServerError, "Erro ao atualizar saldo da conta"
 		}
 	}
 
-	err = tx.Commit()
-	if err != nil {
-		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
-		return
+	// Movimentação retroativa muda o patrimônio dos dias seguintes
+	if err = staleNetWorthFrom(r.Context(), tx, userID, date); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return http.StatusInternalServerError, "Erro ao invalidar patrimônio"
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if err = recordAudit(tx, r, userID, "income", incomeID, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return http.StatusInternalServerError, "Erro ao registrar auditoria"
+	}
+
+	return 0, ""
 }
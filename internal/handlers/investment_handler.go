@@ -0,0 +1,807 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type InvestmentHandler struct {
+	DB *sql.DB
+}
+
+// holdingsValueSQL soma o valor de mercado atual das posições do usuário $1 na
+// moeda base; ativos sem cotação são avaliados pelo preço médio
+const holdingsValueSQL = `
+	SELECT COALESCE(SUM(to_base_currency($1, v.value, a.currency, CURRENT_DATE)), 0)
+	FROM holdings h
+	JOIN accounts a ON a.id = h.account_id AND a.deleted_at IS NULL
+	CROSS JOIN LATERAL (SELECT h.quantity * COALESCE(asset_price($1, h.symbol, CURRENT_DATE), h.average_price) AS value) v
+	WHERE h.user_id = $1`
+
+type holdingRequest struct {
+	AccountID           int64   `json:"account_id"`
+	Symbol              string  `json:"symbol"`
+	Name                string  `json:"name"`
+	AssetClass          string  `json:"asset_class"`
+	InitialQuantity     float64 `json:"initial_quantity"`
+	InitialAveragePrice float64 `json:"initial_average_price"`
+	StartDate           string  `json:"start_date"` // padrão: hoje
+}
+
+type operationRequest struct {
+	HoldingID   int64   `json:"holding_id"`
+	Type        string  `json:"type"`
+	Quantity    float64 `json:"quantity"`
+	Price       float64 `json:"price"`
+	Fees        float64 `json:"fees"`
+	Amount      float64 `json:"amount"` // proventos: valor bruto (padrão: quantidade × valor por cota)
+	Date        string  `json:"date"`   // padrão: hoje
+	Description string  `json:"description"`
+}
+
+// normalizeSymbol padroniza tickers e nomes de títulos (maiúsculas, espaços simples)
+func normalizeSymbol(symbol string) string {
+	return strings.ToUpper(strings.Join(strings.Fields(symbol), " "))
+}
+
+func validateAssetClass(class string) bool {
+	switch class {
+	case "acao", "fii", "etf", "bdr", "renda_fixa", "tesouro", "fundo", "cripto", "outro":
+		return true
+	}
+	return false
+}
+
+// validateHolding normaliza e valida o cadastro de um ativo
+func validateHolding(req holdingRequest) (models.Holding, error) {
+	h := models.Holding{
+		AccountID:           req.AccountID,
+		Symbol:              normalizeSymbol(req.Symbol),
+		Name:                strings.TrimSpace(req.Name),
+		AssetClass:          strings.TrimSpace(req.AssetClass),
+		InitialQuantity:     req.InitialQuantity,
+		InitialAveragePrice: req.InitialAveragePrice,
+		StartDate:           time.Now().UTC().Truncate(24 * time.Hour),
+	}
+
+	if h.Symbol == "" || len(h.Symbol) > 60 {
+		return h, errors.New("Símbolo é obrigatório (até 60 caracteres)")
+	}
+	if !validateAssetClass(h.AssetClass) {
+		return h, errors.New("Classe inválida, use acao, fii, etf, bdr, renda_fixa, tesouro, fundo, cripto ou outro")
+	}
+	if h.InitialQuantity < 0 || h.InitialAveragePrice < 0 {
+		return h, errors.New("Posição inicial não pode ser negativa")
+	}
+	if h.InitialQuantity == 0 {
+		h.InitialAveragePrice = 0
+	}
+	if strings.TrimSpace(req.StartDate) != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return h, errors.New("Data inválida, use YYYY-MM-DD")
+		}
+		h.StartDate = parsed
+	}
+	return h, nil
+}
+
+// validateOperation normaliza e valida uma compra, venda ou provento
+func validateOperation(req operationRequest) (models.InvestmentOperation, error) {
+	op := models.InvestmentOperation{
+		HoldingID:   req.HoldingID,
+		Type:        req.Type,
+		Quantity:    req.Quantity,
+		Price:       req.Price,
+		Fees:        req.Fees,
+		Amount:      req.Amount,
+		Description: strings.TrimSpace(req.Description),
+		Date:        time.Now().UTC().Truncate(24 * time.Hour),
+	}
+
+	if op.HoldingID == 0 {
+		return op, errors.New("Ativo é obrigatório")
+	}
+	if op.Quantity < 0 || op.Price < 0 || op.Fees < 0 || op.Amount < 0 {
+		return op, errors.New("Valores não podem ser negativos")
+	}
+	switch op.Type {
+	case operationBuy, operationSell:
+		if op.Quantity <= 0 || op.Price <= 0 {
+			return op, errors.New("Quantidade e preço devem ser maiores que zero")
+		}
+	case operationDividend:
+		if op.Amount == 0 {
+			op.Amount = roundMoney(op.Quantity * op.Price)
+		}
+		if op.Amount <= 0 {
+			return op, errors.New("Informe o valor do provento (amount) ou quantidade e valor por cota")
+		}
+		if op.Fees > op.Amount {
+			return op, errors.New("Imposto retido maior que o provento")
+		}
+	default:
+		return op, errors.New("Tipo inválido, use buy, sell ou dividend")
+	}
+	if strings.TrimSpace(req.Date) != "" {
+		parsed, err := time.Parse("2006-01-02", req.Date)
+		if err != nil {
+			return op, errors.New("Data inválida, use YYYY-MM-DD")
+		}
+		op.Date = parsed
+	}
+
+	op.Amount = operationCash(op)
+	return op, nil
+}
+
+// checkInvestmentAccount confere se a conta é do usuário e do tipo investimento.
+// Retorna status 0 quando está tudo certo.
+func checkInvestmentAccount(ctx context.Context, q rowQueryer, userID int, accountID int64) (int, string) {
+	var accType string
+	err := q.QueryRowContext(ctx, `SELECT type FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, accountID, userID).Scan(&accType)
+	if err == sql.ErrNoRows {
+		return http.StatusForbidden, "Conta inválida"
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao validar conta"
+	}
+	if accType != "investimento" {
+		return http.StatusBadRequest, "Ativos só podem ser cadastrados em contas do tipo investimento"
+	}
+	return 0, ""
+}
+
+// recalcHolding refaz quantidade, preço médio e lucro das vendas de um ativo a partir
+// das operações gravadas. Retorna status 0 quando está tudo certo.
+func recalcHolding(ctx context.Context, tx *sql.Tx, userID int, holdingID int64) (int, string) {
+	var initialQty, initialAvg float64
+	var startDate time.Time
+	err := tx.QueryRowContext(ctx, `
+		SELECT initial_quantity, initial_average_price, start_date
+		FROM holdings WHERE id = $1 AND user_id = $2
+		FOR UPDATE
+	`, holdingID, userID).Scan(&initialQty, &initialAvg, &startDate)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Ativo não encontrado"
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar ativo"
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, type, quantity, price, fees, date
+		FROM investment_operations
+		WHERE holding_id = $1
+		ORDER BY date, id
+	`, holdingID)
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar operações"
+	}
+	ops := []models.InvestmentOperation{}
+	for rows.Next() {
+		var op models.InvestmentOperation
+		if err := rows.Scan(&op.ID, &op.Type, &op.Quantity, &op.Price, &op.Fees, &op.Date); err != nil {
+			rows.Close()
+			return http.StatusInternalServerError, "Erro ao ler operações"
+		}
+		ops = append(ops, op)
+	}
+	rows.Close()
+
+	qty, avg, gains, err := replayHolding(initialQty, initialAvg, startDate, ops)
+	if err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE holdings SET quantity = $1, average_price = $2 WHERE id = $3`, qty, avg, holdingID); err != nil {
+		return http.StatusInternalServerError, "Erro ao atualizar posição"
+	}
+	for id, gain := range gains {
+		if _, err := tx.ExecContext(ctx, `UPDATE investment_operations SET realized_gain = $1 WHERE id = $2`, gain, id); err != nil {
+			return http.StatusInternalServerError, "Erro ao atualizar lucro realizado"
+		}
+	}
+	return 0, ""
+}
+
+// GetHoldings lista os ativos do usuário (filtro opcional por account_id)
+func (h *InvestmentHandler) GetHoldings(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	baseQuery := `
+		SELECT id, account_id, symbol, COALESCE(name, ''), asset_class, quantity, average_price,
+			initial_quantity, initial_average_price, start_date, created_at
+		FROM holdings WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if accountParam := r.URL.Query().Get("account_id"); accountParam != "" {
+		accountID, err := strconv.ParseInt(accountParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Conta inválida", http.StatusBadRequest)
+			return
+		}
+		baseQuery += " AND account_id = $" + strconv.Itoa(len(args)+1)
+		args = append(args, accountID)
+	}
+	baseQuery += " ORDER BY symbol"
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar ativos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar ativos", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	holdings := []models.Holding{}
+	for rows.Next() {
+		var hd models.Holding
+		if err := rows.Scan(&hd.ID, &hd.AccountID, &hd.Symbol, &hd.Name, &hd.AssetClass, &hd.Quantity, &hd.AveragePrice,
+			&hd.InitialQuantity, &hd.InitialAveragePrice, &hd.StartDate, &hd.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler ativos", http.StatusInternalServerError)
+			return
+		}
+		holdings = append(holdings, hd)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holdings)
+}
+
+// CreateHolding cadastra um ativo em uma conta de investimento; a posição inicial
+// opcional representa o que já existia e não movimenta o caixa da conta
+func (h *InvestmentHandler) CreateHolding(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req holdingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+
+	holding, err := validateHolding(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if status, msg := checkInvestmentAccount(r.Context(), h.DB, userID, holding.AccountID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	holding.Quantity = holding.InitialQuantity
+	holding.AveragePrice = holding.InitialAveragePrice
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO holdings (user_id, account_id, symbol, name, asset_class, initial_quantity, initial_average_price, start_date, quantity, average_price)
+		VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8, $6, $7)
+		RETURNING id, created_at
+	`, userID, holding.AccountID, holding.Symbol, holding.Name, holding.AssetClass,
+		holding.InitialQuantity, holding.InitialAveragePrice, holding.StartDate).Scan(&holding.ID, &holding.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			http.Error(w, "Ativo já cadastrado nesta conta", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao criar ativo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao criar ativo", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "holding", holding.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holding)
+}
+
+// UpdateHolding altera símbolo, nome, classe e posição inicial (a conta não muda)
+func (h *InvestmentHandler) UpdateHolding(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	holdingID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var req holdingRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	holding, err := validateHolding(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "holding", holdingID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar ativo", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `
+		UPDATE holdings
+		SET symbol = $1, name = NULLIF($2, ''), asset_class = $3, initial_quantity = $4, initial_average_price = $5, start_date = $6
+		WHERE id = $7 AND user_id = $8
+	`, holding.Symbol, holding.Name, holding.AssetClass, holding.InitialQuantity, holding.InitialAveragePrice, holding.StartDate, holdingID, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			http.Error(w, "Ativo já cadastrado nesta conta", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao atualizar ativo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao atualizar ativo", "error", err)
+		return
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		http.Error(w, "Ativo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if status, msg := recalcHolding(r.Context(), tx, userID, holdingID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "holding", holdingID, auditUpdate, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	// Operações retroativas mudam o histórico do patrimônio
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Ativo atualizado com sucesso"}`))
+}
+
+// DeleteHolding remove um ativo sem operações (as operações movimentaram o caixa
+// e precisam ser removidas antes)
+func (h *InvestmentHandler) DeleteHolding(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var operations int
+	err := h.DB.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM investment_operations WHERE holding_id = $1 AND user_id = $2`, id, userID).Scan(&operations)
+	if err != nil {
+		http.Error(w, "Erro ao buscar operações", http.StatusInternalServerError)
+		return
+	}
+	if operations > 0 {
+		http.Error(w, "Remova as operações do ativo antes de deletá-lo", http.StatusConflict)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "holding", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar ativo", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM holdings WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao deletar ativo", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "holding", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOperations lista as operações (filtros opcionais: holding_id, account_id, type)
+func (h *InvestmentHandler) GetOperations(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	query := r.URL.Query()
+
+	baseQuery := `
+		SELECT o.id, o.holding_id, h.symbol, o.type, o.quantity, o.price, o.fees, o.cash_amount,
+			o.realized_gain, COALESCE(o.description, ''), o.date
+		FROM investment_operations o
+		JOIN holdings h ON h.id = o.holding_id
+		WHERE o.user_id = $1`
+	args := []interface{}{userID}
+
+	for param, column := range map[string]string{"holding_id": "o.holding_id", "account_id": "h.account_id"} {
+		if v := query.Get(param); v != "" {
+			value, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, "Parâmetro inválido: "+param, http.StatusBadRequest)
+				return
+			}
+			baseQuery += " AND " + column + " = $" + strconv.Itoa(len(args)+1)
+			args = append(args, value)
+		}
+	}
+	if opType := query.Get("type"); opType != "" {
+		baseQuery += " AND o.type = $" + strconv.Itoa(len(args)+1)
+		args = append(args, opType)
+	}
+	baseQuery += " ORDER BY o.date DESC, o.id DESC"
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar operações", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar operações", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	operations := []models.InvestmentOperation{}
+	for rows.Next() {
+		var op models.InvestmentOperation
+		if err := rows.Scan(&op.ID, &op.HoldingID, &op.Symbol, &op.Type, &op.Quantity, &op.Price, &op.Fees, &op.Amount,
+			&op.RealizedGain, &op.Description, &op.Date); err != nil {
+			http.Error(w, "Erro ao ler operações", http.StatusInternalServerError)
+			return
+		}
+		operations = append(operations, op)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(operations)
+}
+
+// CreateOperation registra uma compra, venda ou provento, recalcula a posição e
+// movimenta o caixa da conta de investimento
+func (h *InvestmentHandler) CreateOperation(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req operationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	op, err := validateOperation(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var accountID int64
+	err = tx.QueryRowContext(r.Context(), `SELECT account_id, symbol FROM holdings WHERE id = $1 AND user_id = $2`, op.HoldingID, userID).Scan(&accountID, &op.Symbol)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Ativo inválido", http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar ativo", http.StatusInternalServerError)
+		return
+	}
+
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO investment_operations (user_id, holding_id, type, quantity, price, fees, cash_amount, description, date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+		RETURNING id
+	`, userID, op.HoldingID, op.Type, op.Quantity, op.Price, op.Fees, op.Amount, op.Description, op.Date).Scan(&op.ID)
+	if err != nil {
+		http.Error(w, "Erro ao registrar operação", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar operação", "error", err)
+		return
+	}
+
+	if status, msg := recalcHolding(r.Context(), tx, userID, op.HoldingID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+	if err := tx.QueryRowContext(r.Context(), `SELECT realized_gain FROM investment_operations WHERE id = $1`, op.ID).Scan(&op.RealizedGain); err != nil {
+		http.Error(w, "Erro ao buscar lucro realizado", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, op.Amount, accountID, userID)
+	if err != nil {
+		http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao atualizar saldo da conta", "error", err)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "investment_operation", op.ID, auditCreate, nil); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	// Operações retroativas mudam o histórico do patrimônio
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(op)
+}
+
+// DeleteOperation desfaz uma operação: estorna o caixa e recalcula a posição
+func (h *InvestmentHandler) DeleteOperation(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "investment_operation", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar operação", http.StatusInternalServerError)
+		return
+	}
+
+	var holdingID, accountID int64
+	var cash float64
+	err = tx.QueryRowContext(r.Context(), `
+		DELETE FROM investment_operations o
+		USING holdings h
+		WHERE o.id = $1 AND o.user_id = $2 AND h.id = o.holding_id
+		RETURNING o.holding_id, h.account_id, o.cash_amount
+	`, id, userID).Scan(&holdingID, &accountID, &cash)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Operação não encontrada", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao deletar operação", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao deletar operação", "error", err)
+		return
+	}
+
+	// Remover uma compra pode deixar uma venda posterior sem posição
+	if status, msg := recalcHolding(r.Context(), tx, userID, holdingID); status != 0 {
+		if status == http.StatusBadRequest {
+			msg = "Não é possível remover a operação: " + msg
+		}
+		http.Error(w, msg, status)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, cash, accountID, userID)
+	if err != nil {
+		http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "investment_operation", id, auditDelete, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	// Operações retroativas mudam o histórico do patrimônio
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetPortfolio retorna as posições avaliadas pela última cotação, o ganho não
+// realizado e a alocação por classe de ativo (filtro opcional por account_id).
+// Cada posição fica na moeda da conta; os totais e a alocação, na moeda base.
+func (h *InvestmentHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	accountFilter := ""
+	args := []interface{}{userID}
+	if accountParam := r.URL.Query().Get("account_id"); accountParam != "" {
+		accountID, err := strconv.ParseInt(accountParam, 10, 64)
+		if err != nil {
+			http.Error(w, "Conta inválida", http.StatusBadRequest)
+			return
+		}
+		accountFilter = " AND a.id = $2"
+		args = append(args, accountID)
+	}
+
+	baseCurrency, err := userBaseCurrency(r.Context(), h.DB, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar moeda base", http.StatusInternalServerError)
+		return
+	}
+	portfolio := models.Portfolio{
+		Currency:   baseCurrency,
+		Positions:  []models.Position{},
+		Allocation: []models.AssetAllocation{},
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT h.id, h.account_id, h.symbol, COALESCE(h.name, ''), h.asset_class, a.currency,
+			h.quantity, h.average_price, q.price, q.date,
+			exchange_rate($1, a.currency, user_base_currency($1), CURRENT_DATE)
+		FROM holdings h
+		JOIN accounts a ON a.id = h.account_id AND a.deleted_at IS NULL
+		LEFT JOIN LATERAL (
+			SELECT price, date FROM asset_quotes
+			WHERE user_id = $1 AND symbol = h.symbol AND date <= CURRENT_DATE
+			ORDER BY date DESC
+			LIMIT 1
+		) q ON true
+		WHERE h.user_id = $1 AND h.quantity > 0`+accountFilter+`
+		ORDER BY h.asset_class, h.symbol
+	`, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar posições", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar posições", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	baseValues := []float64{}
+	byClass := make(map[string]float64)
+	classOrder := []string{}
+	for rows.Next() {
+		var p models.Position
+		var lastPrice sql.NullFloat64
+		var priceDate sql.NullTime
+		var rate sql.NullFloat64
+		if err := rows.Scan(&p.HoldingID, &p.AccountID, &p.Symbol, &p.Name, &p.AssetClass, &p.Currency,
+			&p.Quantity, &p.AveragePrice, &lastPrice, &priceDate, &rate); err != nil {
+			http.Error(w, "Erro ao ler posições", http.StatusInternalServerError)
+			return
+		}
+		// Sem cotação a posição não entra no total como se já estivesse na moeda base
+		if !rate.Valid {
+			http.Error(w, missingRateText(p.Currency, baseCurrency, time.Now()), http.StatusUnprocessableEntity)
+			return
+		}
+
+		price := p.AveragePrice
+		if lastPrice.Valid {
+			price = lastPrice.Float64
+			p.LastPrice = &lastPrice.Float64
+			p.PriceDate = &priceDate.Time
+		}
+		p.Cost = roundMoney(p.Quantity * p.AveragePrice)
+		p.MarketValue = roundMoney(p.Quantity * price)
+		p.UnrealizedGain = roundMoney(p.MarketValue - p.Cost)
+		if p.Cost > 0 {
+			p.UnrealizedGainPercent = roundMoney(p.UnrealizedGain / p.Cost * 100)
+		}
+
+		value := p.MarketValue * rate.Float64
+		portfolio.Cost += p.Cost * rate.Float64
+		portfolio.MarketValue += value
+		if _, ok := byClass[p.AssetClass]; !ok {
+			classOrder = append(classOrder, p.AssetClass)
+		}
+		byClass[p.AssetClass] += value
+		baseValues = append(baseValues, value)
+		portfolio.Positions = append(portfolio.Positions, p)
+	}
+
+	for i := range portfolio.Positions {
+		if portfolio.MarketValue > 0 {
+			portfolio.Positions[i].AllocationPercent = roundMoney(baseValues[i] / portfolio.MarketValue * 100)
+		}
+	}
+	for _, class := range classOrder {
+		allocation := models.AssetAllocation{AssetClass: class, MarketValue: roundMoney(byClass[class])}
+		if portfolio.MarketValue > 0 {
+			allocation.Percent = roundMoney(byClass[class] / portfolio.MarketValue * 100)
+		}
+		portfolio.Allocation = append(portfolio.Allocation, allocation)
+	}
+
+	// Lucro das vendas e proventos convertidos pela cotação da data da operação
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT
+			COALESCE(SUM(to_base_currency($1, o.realized_gain, a.currency, o.date)) FILTER (WHERE o.type = 'sell'), 0),
+			COALESCE(SUM(to_base_currency($1, o.cash_amount, a.currency, o.date)) FILTER (WHERE o.type = 'dividend'), 0)
+		FROM investment_operations o
+		JOIN holdings h ON h.id = o.holding_id
+		JOIN accounts a ON a.id = h.account_id AND a.deleted_at IS NULL
+		WHERE o.user_id = $1`+accountFilter, args...).Scan(&portfolio.RealizedGain, &portfolio.Dividends)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao calcular proventos e lucro realizado")
+		return
+	}
+
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT COALESCE(SUM(`+baseBalanceSQL("$1")+`), 0)
+		FROM accounts a
+		WHERE a.user_id = $1 AND a.type = 'investimento' AND a.deleted_at IS NULL`+accountFilter, args...).Scan(&portfolio.Cash)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao calcular caixa")
+		return
+	}
+
+	portfolio.Cost = roundMoney(portfolio.Cost)
+	portfolio.MarketValue = roundMoney(portfolio.MarketValue)
+	portfolio.UnrealizedGain = roundMoney(portfolio.MarketValue - portfolio.Cost)
+	portfolio.RealizedGain = roundMoney(portfolio.RealizedGain)
+	portfolio.Dividends = roundMoney(portfolio.Dividends)
+	portfolio.Cash = roundMoney(portfolio.Cash)
+	portfolio.Total = roundMoney(portfolio.Cash + portfolio.MarketValue)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(portfolio)
+}
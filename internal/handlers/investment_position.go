@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+const (
+	operationBuy      = "buy"
+	operationSell     = "sell"
+	operationDividend = "dividend"
+
+	// Abaixo disso a quantidade é considerada zerada (frações de cripto e fundos)
+	quantityEpsilon = 1e-8
+)
+
+// operationCash é o efeito da operação no caixa da conta: compras debitam
+// quantidade × preço + taxas, vendas creditam o líquido das taxas e proventos
+// creditam o valor recebido menos o imposto retido (fees)
+func operationCash(op models.InvestmentOperation) float64 {
+	switch op.Type {
+	case operationBuy:
+		return -roundMoney(op.Quantity*op.Price + op.Fees)
+	case operationSell:
+		return roundMoney(op.Quantity*op.Price - op.Fees)
+	default:
+		return roundMoney(op.Amount - op.Fees)
+	}
+}
+
+// replayHolding refaz a posição de um ativo a partir da posição inicial (que entra
+// em startDate) e das operações em ordem cronológica. O preço médio inclui as taxas
+// de compra e não muda nas vendas. Retorna quantidade, preço médio e o lucro
+// realizado de cada venda; erro quando uma venda supera a posição na data.
+func replayHolding(initialQty, initialAvg float64, startDate time.Time, ops []models.InvestmentOperation) (float64, float64, map[int64]float64, error) {
+	var qty, avg float64
+	gains := make(map[int64]float64)
+
+	applied := false
+	applyInitial := func() {
+		applied = true
+		if initialQty <= 0 {
+			return
+		}
+		cost := qty*avg + initialQty*initialAvg
+		qty += initialQty
+		avg = cost / qty
+	}
+
+	for _, op := range ops {
+		if !applied && !op.Date.Before(startDate) {
+			applyInitial()
+		}
+
+		switch op.Type {
+		case operationBuy:
+			cost := qty*avg + op.Quantity*op.Price + op.Fees
+			qty += op.Quantity
+			avg = cost / qty
+		case operationSell:
+			if op.Quantity > qty+quantityEpsilon {
+				return 0, 0, nil, fmt.Errorf("Venda de %s em %s maior que a posição na data (%s)",
+					formatQuantity(op.Quantity), op.Date.Format("02/01/2006"), formatQuantity(qty))
+			}
+			gains[op.ID] = roundMoney(op.Quantity*op.Price - op.Fees - op.Quantity*avg)
+			qty -= op.Quantity
+			if qty < quantityEpsilon {
+				qty, avg = 0, 0
+			}
+		}
+	}
+	if !applied {
+		applyInitial()
+	}
+	return qty, avg, gains, nil
+}
+
+func formatQuantity(q float64) string {
+	return strconv.FormatFloat(q, 'f', -1, 64)
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type quoteRequest struct {
+	Symbol string  `json:"symbol"`
+	Date   string  `json:"date"` // padrão: hoje
+	Price  float64 `json:"price"`
+}
+
+// validateQuote normaliza e valida a cotação de um ativo
+func validateQuote(req quoteRequest) (models.AssetQuote, error) {
+	quote := models.AssetQuote{
+		Symbol: normalizeSymbol(req.Symbol),
+		Price:  req.Price,
+		Date:   time.Now().UTC().Truncate(24 * time.Hour),
+	}
+	if quote.Symbol == "" || len(quote.Symbol) > 60 {
+		return quote, errors.New("Símbolo é obrigatório (até 60 caracteres)")
+	}
+	if quote.Price <= 0 {
+		return quote, errors.New("Preço deve ser maior que zero")
+	}
+	if strings.TrimSpace(req.Date) != "" {
+		parsed, err := time.Parse("2006-01-02", strings.TrimSpace(req.Date))
+		if err != nil {
+			return quote, errors.New("Data inválida, use YYYY-MM-DD")
+		}
+		quote.Date = parsed
+	}
+	return quote, nil
+}
+
+// upsertQuote grava a cotação, substituindo a do mesmo ativo e dia. Retorna a
+// cotação substituída (nil quando é nova) para a auditoria.
+func upsertQuote(ctx context.Context, q rowQueryer, userID int, quote *models.AssetQuote) (json.RawMessage, error) {
+	var before []byte
+	err := q.QueryRowContext(ctx, `
+		WITH old AS (
+			SELECT row_to_json(t) AS data FROM asset_quotes t
+			WHERE user_id = $1 AND symbol = $2 AND date = $3
+		)
+		INSERT INTO asset_quotes (user_id, symbol, date, price)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, symbol, date) DO UPDATE SET price = EXCLUDED.price
+		RETURNING id, (SELECT data FROM old)
+	`, userID, quote.Symbol, quote.Date, quote.Price).Scan(&quote.ID, &before)
+	return before, err
+}
+
+// GetQuotes lista as cotações dos ativos
+// Parâmetros opcionais: symbol, from, to (YYYY-MM-DD)
+func (h *InvestmentHandler) GetQuotes(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	query := r.URL.Query()
+
+	baseQuery := `SELECT id, symbol, date, price FROM asset_quotes WHERE user_id = $1`
+	args := []interface{}{userID}
+
+	if symbol := normalizeSymbol(query.Get("symbol")); symbol != "" {
+		baseQuery += " AND symbol = $" + strconv.Itoa(len(args)+1)
+		args = append(args, symbol)
+	}
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		if v := query.Get(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			baseQuery += " AND date " + op + " $" + strconv.Itoa(len(args)+1)
+			args = append(args, date)
+		}
+	}
+	baseQuery += " ORDER BY date DESC, symbol"
+
+	rows, err := h.DB.QueryContext(r.Context(), baseQuery, args...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotações", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar cotações", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	quotes := []models.AssetQuote{}
+	for rows.Next() {
+		var quote models.AssetQuote
+		if err := rows.Scan(&quote.ID, &quote.Symbol, &quote.Date, &quote.Price); err != nil {
+			http.Error(w, "Erro ao ler cotações", http.StatusInternalServerError)
+			return
+		}
+		quotes = append(quotes, quote)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quotes)
+}
+
+// CreateQuote cadastra (ou substitui) a cotação de um ativo em uma data
+func (h *InvestmentHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req quoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	quote, err := validateQuote(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := upsertQuote(r.Context(), tx, userID, &quote)
+	if err != nil {
+		http.Error(w, "Erro ao salvar cotação", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao salvar cotação", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "asset_quote", quote.ID, upsertAction(before), before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(quote)
+}
+
+// ImportQuotes importa cotações de ativos de um CSV com as colunas date, symbol,
+// price, no mesmo formato da importação de câmbio
+func (h *InvestmentHandler) ImportQuotes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	records, semicolon, status, msg := readImportCSV(w, r)
+	if status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	imported := 0
+	importErrors := []ImportLineError{}
+	for i, record := range records {
+		line := i + 1
+		if skipImportLine(i, record, "date") {
+			continue
+		}
+		if len(record) < 3 {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: "Esperado: date, symbol, price"})
+			continue
+		}
+
+		price, err := parseImportDecimal(record[2], semicolon)
+		if err != nil {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: "Preço inválido"})
+			continue
+		}
+		quote, err := validateQuote(quoteRequest{Date: record[0], Symbol: record[1], Price: price})
+		if err != nil {
+			importErrors = append(importErrors, ImportLineError{Line: line, Error: err.Error()})
+			continue
+		}
+		before, err := upsertQuote(r.Context(), tx, userID, &quote)
+		if err != nil {
+			http.Error(w, "Erro ao salvar cotações", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao salvar cotações", "error", err)
+			return
+		}
+		if err := recordAudit(tx, r, userID, "asset_quote", quote.ID, upsertAction(before), before); err != nil {
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+			return
+		}
+		imported++
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if imported > 0 {
+		if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"imported": imported,
+		"errors":   importErrors,
+	})
+}
+
+func (h *InvestmentHandler) DeleteQuote(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "asset_quote", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar cotação", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM asset_quotes WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao deletar cotação", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "asset_quote", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
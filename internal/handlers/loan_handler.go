@@ -0,0 +1,788 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
+)
+
+type LoanHandler struct {
+	DB *sql.DB
+}
+
+// loansBalanceSQL soma o saldo devedor atual dos empréstimos do usuário $1, na moeda base
+const loansBalanceSQL = `SELECT COALESCE(SUM(to_base_currency($1, outstanding_balance, currency, CURRENT_DATE)), 0) FROM loans WHERE user_id = $1`
+
+type loanRequest struct {
+	Name       string  `json:"name"`
+	Lender     string  `json:"lender"`
+	Principal  float64 `json:"principal"`
+	AnnualRate float64 `json:"annual_rate"`
+	TermMonths int     `json:"term_months"`
+	System     string  `json:"system"`
+	StartDate  string  `json:"start_date"` // padrão: hoje
+	AccountID  *int64  `json:"account_id"`
+	Currency   string  `json:"currency"` // padrão: moeda da conta ou moeda base
+}
+
+type loanPaymentRequest struct {
+	LoanID        int64    `json:"loan_id"`
+	ExpenseID     *int64   `json:"expense_id"`     // vincula um gasto já lançado em vez de criar um
+	AccountID     *int64   `json:"account_id"`     // padrão: conta do empréstimo ou Carteira Geral
+	Amount        float64  `json:"amount"`         // na moeda do empréstimo; padrão: valor da próxima parcela
+	AccountAmount *float64 `json:"account_amount"` // valor na moeda da conta, quando difere
+	Date          string   `json:"date"`           // padrão: hoje
+	PaymentMethod string   `json:"payment_method"`
+	Description   string   `json:"description"`
+}
+
+const loanColumns = `id, name, COALESCE(lender, ''), principal, annual_rate, term_months, system, start_date, account_id, currency, created_at`
+
+func scanLoan(row interface{ Scan(...interface{}) error }, loan *models.Loan) error {
+	return row.Scan(&loan.ID, &loan.Name, &loan.Lender, &loan.Principal, &loan.AnnualRate, &loan.TermMonths,
+		&loan.System, &loan.StartDate, &loan.AccountID, &loan.Currency, &loan.CreatedAt)
+}
+
+// validateLoan normaliza e valida o cadastro de um empréstimo
+func validateLoan(req loanRequest) (models.Loan, error) {
+	loan := models.Loan{
+		Name:       strings.TrimSpace(req.Name),
+		Lender:     strings.TrimSpace(req.Lender),
+		Principal:  roundMoney(req.Principal),
+		AnnualRate: req.AnnualRate,
+		TermMonths: req.TermMonths,
+		System:     strings.ToLower(strings.TrimSpace(req.System)),
+		StartDate:  time.Now().UTC().Truncate(24 * time.Hour),
+		AccountID:  req.AccountID,
+	}
+
+	if loan.Name == "" {
+		return loan, errors.New("Nome é obrigatório")
+	}
+	if loan.Principal <= 0 {
+		return loan, errors.New("Valor financiado deve ser maior que zero")
+	}
+	if loan.AnnualRate < 0 {
+		return loan, errors.New("Taxa de juros não pode ser negativa")
+	}
+	if loan.TermMonths < 1 || loan.TermMonths > 600 {
+		return loan, errors.New("Prazo deve estar entre 1 e 600 meses")
+	}
+	if loan.System != loanSystemSAC && loan.System != loanSystemPrice {
+		return loan, errors.New("Sistema de amortização inválido, use sac ou price")
+	}
+	if strings.TrimSpace(req.StartDate) != "" {
+		parsed, err := time.Parse("2006-01-02", req.StartDate)
+		if err != nil {
+			return loan, errors.New("Data inválida, use YYYY-MM-DD")
+		}
+		loan.StartDate = parsed
+	}
+	// Sem moeda o cadastro usa a da conta (ou a base) e a alteração mantém a atual
+	if strings.TrimSpace(req.Currency) != "" {
+		code, ok := normalizeCurrency(req.Currency)
+		if !ok {
+			return loan, errors.New("Moeda inválida, use o código ISO de três letras (ex: BRL, USD)")
+		}
+		loan.Currency = code
+	}
+	return loan, nil
+}
+
+// loadLoanPayments busca os pagamentos de vários empréstimos, em ordem de data,
+// com valor e data do gasto vinculado (o gasto fica na moeda do empréstimo, ver
+// checkLoanCurrency)
+func loadLoanPayments(ctx context.Context, q queryer, loanIDs []int64) (map[int64][]models.LoanPayment, error) {
+	payments := make(map[int64][]models.LoanPayment)
+	if len(loanIDs) == 0 {
+		return payments, nil
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT p.loan_id, p.id, p.expense_id, p.number, e.date, e.amount, p.principal, p.interest, p.balance_after
+		FROM loan_payments p
+		JOIN expenses e ON e.id = p.expense_id AND e.deleted_at IS NULL
+		WHERE p.loan_id = ANY($1)
+		ORDER BY p.loan_id, e.date, e.id
+	`, pq.Array(loanIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.LoanPayment
+		if err := rows.Scan(&p.LoanID, &p.ID, &p.ExpenseID, &p.Number, &p.Date, &p.Amount, &p.Principal, &p.Interest, &p.BalanceAfter); err != nil {
+			return nil, err
+		}
+		payments[p.LoanID] = append(payments[p.LoanID], p)
+	}
+	return payments, rows.Err()
+}
+
+// loadLoans busca os empréstimos do usuário (todos, ou só loanID quando > 0) com
+// os campos calculados preenchidos; devolve também os pagamentos de cada um
+func loadLoans(ctx context.Context, db *sql.DB, userID int, loanID int64) ([]models.Loan, map[int64][]models.LoanPayment, error) {
+	query := `SELECT ` + loanColumns + ` FROM loans WHERE user_id = $1`
+	args := []interface{}{userID}
+	if loanID > 0 {
+		query += " AND id = $2"
+		args = append(args, loanID)
+	}
+	query += " ORDER BY start_date DESC, id DESC"
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	loans := []models.Loan{}
+	ids := []int64{}
+	for rows.Next() {
+		var loan models.Loan
+		if err := scanLoan(rows, &loan); err != nil {
+			return nil, nil, err
+		}
+		loans = append(loans, loan)
+		ids = append(ids, loan.ID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	payments, err := loadLoanPayments(ctx, db, ids)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range loans {
+		fillLoanSchedule(&loans[i], payments[loans[i].ID])
+	}
+	return loans, payments, nil
+}
+
+// recalcLoan refaz a divisão juros/amortização de todos os pagamentos de um
+// empréstimo e o saldo devedor (após incluir, alterar ou remover pagamentos)
+func recalcLoan(ctx context.Context, tx *sql.Tx, loanID int64) error {
+	var principal, annualRate float64
+	err := tx.QueryRowContext(ctx, `SELECT principal, annual_rate FROM loans WHERE id = $1 FOR UPDATE`, loanID).Scan(&principal, &annualRate)
+	if err != nil {
+		return err
+	}
+
+	payments, err := loadLoanPayments(ctx, tx, []int64{loanID})
+	if err != nil {
+		return err
+	}
+	list := payments[loanID]
+	balance := splitLoanPayments(principal, loanMonthlyRate(annualRate), list)
+
+	for _, p := range list {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE loan_payments SET number = $1, principal = $2, interest = $3, balance_after = $4 WHERE id = $5
+		`, p.Number, p.Principal, p.Interest, p.BalanceAfter, p.ID)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = tx.ExecContext(ctx, `UPDATE loans SET outstanding_balance = $1 WHERE id = $2`, balance, loanID)
+	return err
+}
+
+// linkedLoan retorna o empréstimo pago pelo gasto, se houver
+func linkedLoan(ctx context.Context, q rowQueryer, expenseID interface{}) (*int64, error) {
+	var loanID int64
+	err := q.QueryRowContext(ctx, `SELECT loan_id FROM loan_payments WHERE expense_id = $1`, expenseID).Scan(&loanID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &loanID, nil
+}
+
+// checkLoanCurrency garante que o gasto de uma parcela está na moeda do empréstimo,
+// já que o valor dele é abatido direto do saldo devedor
+func checkLoanCurrency(ctx context.Context, q rowQueryer, loanID int64, currency string) (int, string) {
+	var loanCurrency string
+	if err := q.QueryRowContext(ctx, `SELECT currency FROM loans WHERE id = $1`, loanID).Scan(&loanCurrency); err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar empréstimo"
+	}
+	if currency != loanCurrency {
+		return http.StatusBadRequest, fmt.Sprintf("Parcela deve estar na moeda do empréstimo (%s)", loanCurrency)
+	}
+	return 0, ""
+}
+
+func (h *LoanHandler) checkAccount(ctx context.Context, userID int, accountID *int64) (int, string) {
+	if accountID == nil {
+		return 0, ""
+	}
+	var count int
+	if err := h.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, *accountID, userID).Scan(&count); err != nil {
+		return http.StatusInternalServerError, "Erro ao validar conta"
+	}
+	if count == 0 {
+		return http.StatusForbidden, "Conta inválida"
+	}
+	return 0, ""
+}
+
+// GetLoans lista os empréstimos com saldo devedor, juros pagos, próxima parcela e quitação prevista
+func (h *LoanHandler) GetLoans(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	loans, _, err := loadLoans(r.Context(), h.DB, userID, 0)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar empréstimos", "error", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loans)
+}
+
+func (h *LoanHandler) CreateLoan(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req loanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	loan, err := validateLoan(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if status, msg := h.checkAccount(r.Context(), userID, loan.AccountID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(r.Context(), `
+		INSERT INTO loans (user_id, name, lender, principal, annual_rate, term_months, system, start_date, account_id, outstanding_balance, currency)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, $9, $4,
+			COALESCE(NULLIF($10, ''), (SELECT currency FROM accounts WHERE id = $9), user_base_currency($1)))
+		RETURNING id, currency, created_at
+	`, userID, loan.Name, loan.Lender, loan.Principal, loan.AnnualRate, loan.TermMonths, loan.System, loan.StartDate, loan.AccountID, loan.Currency).Scan(&loan.ID, &loan.Currency, &loan.CreatedAt)
+	if err != nil {
+		http.Error(w, "Erro ao criar empréstimo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao criar empréstimo", "error", err)
+		return
+	}
+	// O saldo devedor entra no patrimônio a partir da contratação
+	if err := staleNetWorthFrom(r.Context(), tx, userID, loan.StartDate); err != nil {
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "loan", loan.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	fillLoanSchedule(&loan, nil)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(loan)
+}
+
+// UpdateLoan altera o contrato; os pagamentos já feitos são redistribuídos
+func (h *LoanHandler) UpdateLoan(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	loanID, err := strconv.ParseInt(pathParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var req loanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	loan, err := validateLoan(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if status, msg := h.checkAccount(r.Context(), userID, loan.AccountID); status != 0 {
+		http.Error(w, msg, status)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var oldStartDate time.Time
+	var oldCurrency string
+	var paymentCount int
+	err = tx.QueryRowContext(r.Context(), `
+		SELECT start_date, currency, (SELECT COUNT(*) FROM loan_payments WHERE loan_id = loans.id)
+		FROM loans WHERE id = $1 AND user_id = $2
+	`, loanID, userID).Scan(&oldStartDate, &oldCurrency, &paymentCount)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo", http.StatusInternalServerError)
+		return
+	}
+	if loan.Currency == "" {
+		loan.Currency = oldCurrency
+	}
+	// Os pagamentos já lançados estão na moeda atual
+	if loan.Currency != oldCurrency && paymentCount > 0 {
+		http.Error(w, "Empréstimo com pagamentos não pode mudar de moeda", http.StatusConflict)
+		return
+	}
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "loan", loanID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `
+		UPDATE loans
+		SET name = $1, lender = NULLIF($2, ''), principal = $3, annual_rate = $4, term_months = $5, system = $6, start_date = $7, account_id = $8, currency = $9
+		WHERE id = $10 AND user_id = $11
+	`, loan.Name, loan.Lender, loan.Principal, loan.AnnualRate, loan.TermMonths, loan.System, loan.StartDate, loan.AccountID, loan.Currency, loanID, userID)
+	if err != nil {
+		http.Error(w, "Erro ao atualizar empréstimo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao atualizar empréstimo", "error", err)
+		return
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+
+	if err := recalcLoan(r.Context(), tx, loanID); err != nil {
+		http.Error(w, "Erro ao recalcular pagamentos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao recalcular pagamentos", "error", err)
+		return
+	}
+	// O contrato muda o saldo devedor desde a contratação (a antiga ou a nova)
+	if err := staleNetWorthFrom(r.Context(), tx, userID, oldStartDate, loan.StartDate); err != nil {
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "loan", loanID, auditUpdate, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Empréstimo atualizado com sucesso"}`))
+}
+
+// DeleteLoan remove o empréstimo; os gastos dos pagamentos continuam lançados
+func (h *LoanHandler) DeleteLoan(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "loan", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo", http.StatusInternalServerError)
+		return
+	}
+
+	var startDate time.Time
+	err = tx.QueryRowContext(r.Context(), `DELETE FROM loans WHERE id = $1 AND user_id = $2 RETURNING start_date`, id, userID).Scan(&startDate)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao deletar empréstimo", http.StatusInternalServerError)
+		return
+	}
+	// O saldo devedor sai do patrimônio desde a contratação
+	if err := staleNetWorthFrom(r.Context(), tx, userID, startDate); err != nil {
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "loan", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetLoanSchedule retorna o empréstimo e a tabela de amortização: parcelas pagas
+// com os valores reais e as restantes projetadas a partir do saldo devedor
+func (h *LoanHandler) GetLoanSchedule(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	loanID, err := strconv.ParseInt(pathParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	loans, payments, err := loadLoans(r.Context(), h.DB, userID, loanID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar empréstimo", "error", err)
+		return
+	}
+	if len(loans) == 0 {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	loan := loans[0]
+	schedule := fillLoanSchedule(&loan, payments[loan.ID])
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"loan":         loan,
+		"installments": schedule,
+	})
+}
+
+// CreateLoanPayment registra o pagamento de uma parcela: lança um gasto na
+// categoria dividas (debitando a conta) ou vincula um gasto já existente
+func (h *LoanHandler) CreateLoanPayment(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req loanPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	// Em /v1/loans/{id}/payments o empréstimo vem do caminho
+	if id := r.PathValue("id"); id != "" {
+		loanID, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			http.Error(w, "ID inválido", http.StatusBadRequest)
+			return
+		}
+		req.LoanID = loanID
+	}
+	if req.Amount < 0 {
+		http.Error(w, "Valor deve ser maior que zero", http.StatusBadRequest)
+		return
+	}
+
+	loans, payments, err := loadLoans(r.Context(), h.DB, userID, req.LoanID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar empréstimo", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar empréstimo", "error", err)
+		return
+	}
+	if len(loans) == 0 {
+		http.Error(w, "Empréstimo não encontrado", http.StatusNotFound)
+		return
+	}
+	loan := loans[0]
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	var expenseID int64
+	if req.ExpenseID != nil {
+		var currency string
+		err := tx.QueryRowContext(r.Context(), `SELECT currency FROM expenses WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`, *req.ExpenseID, userID).Scan(&currency)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Gasto inválido", http.StatusForbidden)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Erro ao buscar gasto", http.StatusInternalServerError)
+			return
+		}
+		if status, msg := checkLoanCurrency(r.Context(), tx, loan.ID, currency); status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+		expenseID = *req.ExpenseID
+	} else {
+		if loan.NextInstallment == nil {
+			http.Error(w, "Empréstimo já quitado", http.StatusBadRequest)
+			return
+		}
+		amount := roundMoney(req.Amount)
+		if amount == 0 {
+			amount = loan.NextInstallment.Amount
+		}
+
+		date := time.Now().UTC()
+		if strings.TrimSpace(req.Date) != "" {
+			parsed, err := time.Parse("2006-01-02", req.Date)
+			if err != nil {
+				http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			date = parsed
+		}
+
+		accountID := req.AccountID
+		if accountID == nil {
+			accountID = loan.AccountID
+		}
+		if accountID == nil {
+			accountHandler := &AccountHandler{DB: h.DB}
+			defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(r.Context(), userID)
+			if err != nil {
+				http.Error(w, "Erro ao criar conta padrão", http.StatusInternalServerError)
+				slog.ErrorContext(r.Context(), "Erro ao criar conta padrão", "error", err)
+				return
+			}
+			accountID = &defaultAccountID
+		}
+
+		// A parcela é lançada na moeda do empréstimo; conta em outra moeda é
+		// debitada pela conversão (ou por account_amount)
+		currency := loan.Currency
+		accountAmount, status, msg := resolveTransactionCurrency(r.Context(), tx, userID, *accountID, &currency, amount, req.AccountAmount, date)
+		if status != 0 {
+			http.Error(w, msg, status)
+			return
+		}
+
+		description := strings.TrimSpace(req.Description)
+		if description == "" {
+			description = fmt.Sprintf("Parcela %d/%d - %s", len(payments[loan.ID])+1, loan.TermMonths, loan.Name)
+		}
+		paymentMethod := req.PaymentMethod
+		if paymentMethod == "" {
+			paymentMethod = "boleto"
+		}
+
+		err = tx.QueryRowContext(r.Context(), `
+			INSERT INTO expenses (description, amount, category, "group", payment_method, date, user_id, account_id, currency, account_amount)
+			VALUES ($1, $2, 'dividas', 'investimento', $3, $4, $5, $6, $7, $8)
+			RETURNING id
+		`, description, amount, paymentMethod, date, userID, accountID, currency, accountAmount).Scan(&expenseID)
+		if err != nil {
+			http.Error(w, "Erro ao lançar pagamento", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao lançar pagamento", "error", err)
+			return
+		}
+
+		_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance - $1 WHERE id = $2 AND user_id = $3`, accountEffect(amount, accountAmount), accountID, userID)
+		if err != nil {
+			http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao atualizar saldo da conta", "error", err)
+			return
+		}
+
+		if err := recordAudit(tx, r, userID, "expense", expenseID, auditCreate, nil); err != nil {
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+			return
+		}
+	}
+
+	var paymentID int64
+	err = tx.QueryRowContext(r.Context(), `INSERT INTO loan_payments (loan_id, expense_id) VALUES ($1, $2) RETURNING id`, loan.ID, expenseID).Scan(&paymentID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			http.Error(w, "Gasto já vinculado a um empréstimo", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao registrar pagamento", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar pagamento", "error", err)
+		return
+	}
+
+	if err := recalcLoan(r.Context(), tx, loan.ID); err != nil {
+		http.Error(w, "Erro ao recalcular pagamentos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao recalcular pagamentos", "error", err)
+		return
+	}
+
+	payment := models.LoanPayment{ID: paymentID, LoanID: loan.ID, ExpenseID: expenseID}
+	err = tx.QueryRowContext(r.Context(), `
+		SELECT p.number, e.date, e.amount, p.principal, p.interest, p.balance_after
+		FROM loan_payments p
+		JOIN expenses e ON e.id = p.expense_id
+		WHERE p.id = $1
+	`, paymentID).Scan(&payment.Number, &payment.Date, &payment.Amount, &payment.Principal, &payment.Interest, &payment.BalanceAfter)
+	if err != nil {
+		http.Error(w, "Erro ao buscar pagamento", http.StatusInternalServerError)
+		return
+	}
+
+	// Pagamento retroativo muda o saldo da conta e o saldo devedor dos dias seguintes
+	if err := staleNetWorthFrom(r.Context(), tx, userID, payment.Date); err != nil {
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "loan_payment", paymentID, auditCreate, nil); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	if req.ExpenseID == nil {
+		metrics.TransactionsCreated.WithLabelValues("expense").Inc()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(payment)
+}
+
+// DeleteLoanPayment desvincula um pagamento do empréstimo; o gasto continua
+// lançado (para estornar a conta, delete o gasto)
+func (h *LoanHandler) DeleteLoanPayment(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	// Em /v1/loans/{loan}/payments/{id} o pagamento precisa pertencer ao empréstimo da URL
+	query := `
+		DELETE FROM loan_payments p
+		USING loans l
+		WHERE p.id = $1 AND l.id = p.loan_id AND l.user_id = $2`
+	args := []any{id, userID}
+	if loan := r.PathValue("loan"); loan != "" {
+		pathLoanID, err := strconv.ParseInt(loan, 10, 64)
+		if err != nil {
+			http.Error(w, "ID do empréstimo inválido", http.StatusBadRequest)
+			return
+		}
+		query += ` AND p.loan_id = $3`
+		args = append(args, pathLoanID)
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "loan_payment", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar pagamento", http.StatusInternalServerError)
+		return
+	}
+
+	var loanID int64
+	var paymentDate time.Time
+	err = tx.QueryRowContext(r.Context(), query+` RETURNING p.loan_id, (SELECT date FROM expenses WHERE id = p.expense_id)`, args...).Scan(&loanID, &paymentDate)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Pagamento não encontrado", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao deletar pagamento", http.StatusInternalServerError)
+		return
+	}
+
+	if err := recalcLoan(r.Context(), tx, loanID); err != nil {
+		http.Error(w, "Erro ao recalcular pagamentos", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao recalcular pagamentos", "error", err)
+		return
+	}
+	// Sem o pagamento o saldo devedor volta a subir desde a data dele
+	if err := staleNetWorthFrom(r.Context(), tx, userID, paymentDate); err != nil {
+		http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		return
+	}
+
+	if err := recordAudit(tx, r, userID, "loan_payment", id, auditDelete, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"math"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+const (
+	loanSystemSAC   = "sac"
+	loanSystemPrice = "price"
+)
+
+// loanMonthlyRate converte os juros anuais (%) na taxa mensal equivalente
+func loanMonthlyRate(annualRate float64) float64 {
+	if annualRate <= 0 {
+		return 0
+	}
+	return math.Pow(1+annualRate/100, 1.0/12) - 1
+}
+
+// splitLoanPayments divide cada pagamento (em ordem) entre juros sobre o saldo
+// devedor e amortização, numerando-os. Pagamentos maiores que a parcela amortizam
+// mais; menores que os juros aumentam o saldo. Retorna o saldo devedor final.
+func splitLoanPayments(principal, monthlyRate float64, payments []models.LoanPayment) float64 {
+	balance := principal
+	for i := range payments {
+		p := &payments[i]
+		p.Number = i + 1
+		p.Interest = roundMoney(balance * monthlyRate)
+		p.Principal = roundMoney(math.Min(p.Amount-p.Interest, balance))
+		balance = roundMoney(balance - p.Principal)
+		p.BalanceAfter = balance
+	}
+	return balance
+}
+
+// projectInstallments gera as parcelas restantes para quitar balance em months
+// meses: amortização constante (SAC) ou parcela constante (Price). A última
+// parcela absorve os arredondamentos.
+func projectInstallments(balance, monthlyRate float64, months int, system string, firstNumber int, startDate time.Time) []models.LoanInstallment {
+	installments := []models.LoanInstallment{}
+	if balance <= 0 || months <= 0 {
+		return installments
+	}
+
+	payment := balance / float64(months)
+	if system == loanSystemPrice && monthlyRate > 0 {
+		payment = balance * monthlyRate / (1 - math.Pow(1+monthlyRate, -float64(months)))
+	}
+	amortization := roundMoney(balance / float64(months))
+
+	for k := 0; k < months; k++ {
+		number := firstNumber + k
+		inst := models.LoanInstallment{
+			Number:   number,
+			DueDate:  startDate.AddDate(0, number, 0),
+			Interest: roundMoney(balance * monthlyRate),
+		}
+		if system == loanSystemPrice {
+			inst.Principal = roundMoney(payment - inst.Interest)
+		} else {
+			inst.Principal = amortization
+		}
+		if k == months-1 || inst.Principal > balance {
+			inst.Principal = balance
+		}
+		inst.Amount = roundMoney(inst.Principal + inst.Interest)
+		balance = roundMoney(balance - inst.Principal)
+		inst.Balance = balance
+		installments = append(installments, inst)
+	}
+	return installments
+}
+
+// fillLoanSchedule monta a tabela de amortização (parcelas pagas + projetadas a partir
+// do saldo atual no prazo restante) e preenche os campos calculados do empréstimo.
+// payments precisa estar dividido por splitLoanPayments.
+func fillLoanSchedule(loan *models.Loan, payments []models.LoanPayment) []models.LoanInstallment {
+	schedule := []models.LoanInstallment{}
+	loan.OutstandingBalance = loan.Principal
+	loan.PrincipalPaid = 0
+	loan.InterestPaid = 0
+
+	for _, p := range payments {
+		paymentID, expenseID, paidDate := p.ID, p.ExpenseID, p.Date
+		schedule = append(schedule, models.LoanInstallment{
+			Number:    p.Number,
+			DueDate:   loan.StartDate.AddDate(0, p.Number, 0),
+			Amount:    p.Amount,
+			Principal: p.Principal,
+			Interest:  p.Interest,
+			Balance:   p.BalanceAfter,
+			Paid:      true,
+			PaymentID: &paymentID,
+			ExpenseID: &expenseID,
+			PaidDate:  &paidDate,
+		})
+		loan.OutstandingBalance = p.BalanceAfter
+		loan.PrincipalPaid += p.Principal
+		loan.InterestPaid += p.Interest
+	}
+	loan.PrincipalPaid = roundMoney(loan.PrincipalPaid)
+	loan.InterestPaid = roundMoney(loan.InterestPaid)
+	loan.PaidInstallments = len(payments)
+
+	// Saldo que sobrou depois do prazo vira uma parcela única
+	remaining := loan.TermMonths - len(payments)
+	if loan.OutstandingBalance > 0 && remaining < 1 {
+		remaining = 1
+	}
+	projected := projectInstallments(loan.OutstandingBalance, loanMonthlyRate(loan.AnnualRate), remaining, loan.System, len(payments)+1, loan.StartDate)
+
+	loan.RemainingInstallments = len(projected)
+	loan.RemainingInterest = 0
+	for _, inst := range projected {
+		loan.RemainingInterest += inst.Interest
+	}
+	loan.RemainingInterest = roundMoney(loan.RemainingInterest)
+
+	loan.NextInstallment = nil
+	loan.PayoffDate = nil
+	if len(projected) > 0 {
+		next := projected[0]
+		loan.NextInstallment = &next
+		payoff := projected[len(projected)-1].DueDate
+		loan.PayoffDate = &payoff
+	} else if len(payments) > 0 {
+		payoff := payments[len(payments)-1].Date
+		loan.PayoffDate = &payoff
+	}
+
+	return append(schedule, projected...)
+}
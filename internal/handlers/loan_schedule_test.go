@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+func TestProjectInstallments(t *testing.T) {
+	start := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		balance     float64
+		monthlyRate float64
+		months      int
+		system      string
+		amounts     []float64
+		interests   []float64
+	}{
+		{
+			name:    "sac sem juros: última parcela absorve o arredondamento",
+			balance: 1000, monthlyRate: 0, months: 3, system: loanSystemSAC,
+			amounts:   []float64{333.33, 333.33, 333.34},
+			interests: []float64{0, 0, 0},
+		},
+		{
+			name:    "price sem juros divide o saldo igual ao sac",
+			balance: 1000, monthlyRate: 0, months: 3, system: loanSystemPrice,
+			amounts:   []float64{333.33, 333.33, 333.34},
+			interests: []float64{0, 0, 0},
+		},
+		{
+			name:    "price com juros: parcela constante e última ajustada",
+			balance: 1000, monthlyRate: 0.01, months: 3, system: loanSystemPrice,
+			amounts:   []float64{340.02, 340.02, 340.03},
+			interests: []float64{10, 6.7, 3.37},
+		},
+		{
+			name:    "sac com juros: amortização constante e juros decrescentes",
+			balance: 1200, monthlyRate: 0.01, months: 3, system: loanSystemSAC,
+			amounts:   []float64{412, 408, 404},
+			interests: []float64{12, 8, 4},
+		},
+		{
+			name:    "saldo zerado não gera parcelas",
+			balance: 0, monthlyRate: 0.01, months: 3, system: loanSystemPrice,
+		},
+		{
+			name:    "prazo zerado não gera parcelas",
+			balance: 1000, monthlyRate: 0.01, months: 0, system: loanSystemSAC,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := projectInstallments(tt.balance, tt.monthlyRate, tt.months, tt.system, 1, start)
+			if len(got) != len(tt.amounts) {
+				t.Fatalf("parcelas = %d, esperado %d", len(got), len(tt.amounts))
+			}
+			for i, inst := range got {
+				if inst.Amount != tt.amounts[i] {
+					t.Errorf("parcela %d: valor = %v, esperado %v", i+1, inst.Amount, tt.amounts[i])
+				}
+				if inst.Interest != tt.interests[i] {
+					t.Errorf("parcela %d: juros = %v, esperado %v", i+1, inst.Interest, tt.interests[i])
+				}
+			}
+			if len(got) > 0 && got[len(got)-1].Balance != 0 {
+				t.Errorf("saldo após a última parcela = %v, esperado 0", got[len(got)-1].Balance)
+			}
+		})
+	}
+}
+
+func TestProjectInstallmentsNumbering(t *testing.T) {
+	start := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	got := projectInstallments(500, 0, 2, loanSystemSAC, 3, start)
+	if len(got) != 2 {
+		t.Fatalf("parcelas = %d, esperado 2", len(got))
+	}
+	if got[0].Number != 3 || got[1].Number != 4 {
+		t.Errorf("números = %d, %d, esperado 3, 4", got[0].Number, got[1].Number)
+	}
+	want := time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC)
+	if !got[0].DueDate.Equal(want) {
+		t.Errorf("vencimento = %v, esperado %v", got[0].DueDate, want)
+	}
+}
+
+func TestSplitLoanPayments(t *testing.T) {
+	tests := []struct {
+		name        string
+		principal   float64
+		monthlyRate float64
+		amounts     []float64
+		principals  []float64
+		interests   []float64
+		balance     float64
+	}{
+		{
+			name:      "parcela exata",
+			principal: 1000, monthlyRate: 0.01,
+			amounts:    []float64{340.02, 340.02},
+			principals: []float64{330.02, 333.32},
+			interests:  []float64{10, 6.7},
+			balance:    336.66,
+		},
+		{
+			name:      "pagamento acima da parcela amortiza mais",
+			principal: 1000, monthlyRate: 0.01,
+			amounts:    []float64{500},
+			principals: []float64{490},
+			interests:  []float64{10},
+			balance:    510,
+		},
+		{
+			name:      "pagamento abaixo dos juros aumenta o saldo",
+			principal: 1000, monthlyRate: 0.01,
+			amounts:    []float64{5},
+			principals: []float64{-5},
+			interests:  []float64{10},
+			balance:    1005,
+		},
+		{
+			name:      "pagamento acima do saldo amortiza só o saldo",
+			principal: 1000, monthlyRate: 0.01,
+			amounts:    []float64{2000},
+			principals: []float64{1000},
+			interests:  []float64{10},
+			balance:    0,
+		},
+		{
+			name:      "sem juros tudo amortiza",
+			principal: 1000, monthlyRate: 0,
+			amounts:    []float64{100, 250},
+			principals: []float64{100, 250},
+			interests:  []float64{0, 0},
+			balance:    650,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payments := make([]models.LoanPayment, len(tt.amounts))
+			for i, amount := range tt.amounts {
+				payments[i].Amount = amount
+			}
+
+			balance := splitLoanPayments(tt.principal, tt.monthlyRate, payments)
+			if balance != tt.balance {
+				t.Errorf("saldo devedor = %v, esperado %v", balance, tt.balance)
+			}
+			for i, p := range payments {
+				if p.Number != i+1 {
+					t.Errorf("pagamento %d: número = %d", i+1, p.Number)
+				}
+				if p.Principal != tt.principals[i] {
+					t.Errorf("pagamento %d: amortização = %v, esperado %v", i+1, p.Principal, tt.principals[i])
+				}
+				if p.Interest != tt.interests[i] {
+					t.Errorf("pagamento %d: juros = %v, esperado %v", i+1, p.Interest, tt.interests[i])
+				}
+			}
+			if len(payments) > 0 && payments[len(payments)-1].BalanceAfter != tt.balance {
+				t.Errorf("saldo após o último pagamento = %v, esperado %v", payments[len(payments)-1].BalanceAfter, tt.balance)
+			}
+		})
+	}
+}
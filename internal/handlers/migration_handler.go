@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
@@ -21,18 +22,18 @@ func (h *MigrationHandler) CheckUnlinkedTransactions(w http.ResponseWriter, r *h
 	var totalUnlinkedAmount float64
 
 	// Conta gastos sem account_id
-	h.DB.QueryRow(`
+	h.DB.QueryRowContext(r.Context(), `
 		SELECT COUNT(*), COALESCE(SUM(amount), 0)
 		FROM expenses
-		WHERE user_id = $1 AND account_id IS NULL
+		WHERE user_id = $1 AND account_id IS NULL AND deleted_at IS NULL
 	`, userID).Scan(&unlinkedExpenses, &totalUnlinkedAmount)
 
 	// Soma rendas sem account_id
 	var unlinkedIncomesAmount float64
-	h.DB.QueryRow(`
+	h.DB.QueryRowContext(r.Context(), `
 		SELECT COUNT(*), COALESCE(SUM(amount), 0)
 		FROM incomes
-		WHERE user_id = $1 AND account_id IS NULL
+		WHERE user_id = $1 AND account_id IS NULL AND deleted_at IS NULL
 	`, userID).Scan(&unlinkedIncomes, &unlinkedIncomesAmount)
 
 	totalUnlinkedAmount += unlinkedIncomesAmount
@@ -61,14 +62,14 @@ func (h *MigrationHandler) MigrateUnlinkedTransactions(w http.ResponseWriter, r
 
 	// Cria/busca Carteira Geral
 	accountHandler := &AccountHandler{DB: h.DB}
-	defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(userID)
+	defaultAccountID, err := accountHandler.GetOrCreateDefaultAccount(r.Context(), userID)
 	if err != nil {
 		http.Error(w, "Erro ao criar conta padrão", http.StatusInternalServerError)
 		return
 	}
 
 	// Start transaction
-	tx, err := h.DB.Begin()
+	tx, err := h.DB.BeginTx(r.Context(), nil)
 	if err != nil {
 		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
 		return
@@ -76,10 +77,15 @@ func (h *MigrationHandler) MigrateUnlinkedTransactions(w http.ResponseWriter, r
 	defer tx.Rollback()
 
 	// Migra gastos
-	result, err := tx.Exec(`
+	expenseBefore, err := auditSnapshots(r.Context(), tx, userID, "expense", "t.account_id IS NULL AND t.deleted_at IS NULL")
+	if err != nil {
+		http.Error(w, "Erro ao migrar gastos", http.StatusInternalServerError)
+		return
+	}
+	result, err := tx.ExecContext(r.Context(), `
 		UPDATE expenses
 		SET account_id = $1
-		WHERE user_id = $2 AND account_id IS NULL
+		WHERE user_id = $2 AND account_id IS NULL AND deleted_at IS NULL
 	`, defaultAccountID, userID)
 	if err != nil {
 		http.Error(w, "Erro ao migrar gastos", http.StatusInternalServerError)
@@ -88,10 +94,15 @@ func (h *MigrationHandler) MigrateUnlinkedTransactions(w http.ResponseWriter, r
 	expensesMigrated, _ := result.RowsAffected()
 
 	// Migra rendas
-	result, err = tx.Exec(`
+	incomeBefore, err := auditSnapshots(r.Context(), tx, userID, "income", "t.account_id IS NULL AND t.deleted_at IS NULL")
+	if err != nil {
+		http.Error(w, "Erro ao migrar rendas", http.StatusInternalServerError)
+		return
+	}
+	result, err = tx.ExecContext(r.Context(), `
 		UPDATE incomes
 		SET account_id = $1
-		WHERE user_id = $2 AND account_id IS NULL
+		WHERE user_id = $2 AND account_id IS NULL AND deleted_at IS NULL
 	`, defaultAccountID, userID)
 	if err != nil {
 		http.Error(w, "Erro ao migrar rendas", http.StatusInternalServerError)
@@ -99,11 +110,30 @@ func (h *MigrationHandler) MigrateUnlinkedTransactions(w http.ResponseWriter, r
 	}
 	incomesMigrated, _ := result.RowsAffected()
 
+	migrated := []struct {
+		entityType string
+		rows       []auditRow
+	}{{"expense", expenseBefore}, {"income", incomeBefore}}
+	for _, batch := range migrated {
+		for _, row := range batch.rows {
+			if err := recordAudit(tx, r, userID, batch.entityType, row.ID, auditUpdate, row.Data); err != nil {
+				http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		http.Error(w, "Erro ao confirmar migração", http.StatusInternalServerError)
 		return
 	}
+	// As transações passam a contar no saldo da Carteira Geral desde a data de cada uma
+	if expensesMigrated+incomesMigrated > 0 {
+		if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		}
+	}
 
 	response := map[string]interface{}{
 		"message":           "Transações migradas com sucesso",
@@ -0,0 +1,312 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+type NetWorthHandler struct {
+	DB *sql.DB
+}
+
+// accountEffectsCTE lista cada movimentação que altera o saldo de uma conta do
+// usuário $1 (account_id, date, amount com sinal, na moeda da conta). Aportes em
+// metas sem transferência debitam a conta de origem diretamente e operações de
+// investimento movimentam o caixa da conta de investimento. Gastos e rendas na
+// lixeira já foram estornados do saldo e ficam de fora.
+const accountEffectsCTE = `
+	effects AS (
+		SELECT account_id, date, COALESCE(account_amount, amount) AS amount FROM incomes WHERE user_id = $1 AND account_id IS NOT NULL AND deleted_at IS NULL
+		UNION ALL
+		SELECT account_id, date, -COALESCE(account_amount, amount) FROM expenses WHERE user_id = $1 AND account_id IS NOT NULL AND deleted_at IS NULL
+		UNION ALL
+		SELECT to_account_id, date, COALESCE(to_amount, amount) FROM transfers WHERE user_id = $1 AND to_account_id IS NOT NULL
+		UNION ALL
+		SELECT from_account_id, date, -amount FROM transfers WHERE user_id = $1 AND from_account_id IS NOT NULL
+		UNION ALL
+		SELECT account_id, date, -amount FROM goal_contributions WHERE user_id = $1 AND account_id IS NOT NULL AND transfer_id IS NULL
+		UNION ALL
+		SELECT h.account_id, o.date, o.cash_amount FROM investment_operations o JOIN holdings h ON h.id = o.holding_id WHERE o.user_id = $1
+	),
+	daily AS (
+		SELECT account_id, date, SUM(amount) AS delta FROM effects GROUP BY account_id, date
+	)`
+
+// refreshSnapshots reconstrói os snapshots diários entre from e hoje a partir
+// do saldo atual de cada conta, desfazendo as movimentações posteriores a cada dia.
+func refreshSnapshots(ctx context.Context, db *sql.DB, userID int, from time.Time) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		WITH `+accountEffectsCTE+`,
+		days AS (
+			SELECT generate_series($2::date, CURRENT_DATE, interval '1 day')::date AS date
+		)
+		INSERT INTO account_balance_snapshots (user_id, account_id, date, balance)
+		SELECT $1, a.id, d.date,
+			a.balance - COALESCE((SELECT SUM(delta) FROM daily WHERE daily.account_id = a.id AND daily.date > d.date), 0)
+		FROM accounts a
+		CROSS JOIN days d
+		WHERE a.user_id = $1 AND a.deleted_at IS NULL
+		ON CONFLICT (account_id, date) DO UPDATE SET balance = EXCLUDED.balance
+	`, userID, from)
+	if err != nil {
+		return err
+	}
+
+	// Totais na moeda base do usuário, convertidos pela cotação de cada dia. As
+	// posições em investimentos entram nos ativos pela quantidade e cotação do dia e
+	// o saldo devedor dos empréstimos entra nas dívidas.
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO net_worth_snapshots (user_id, date, assets, liabilities, net_worth)
+		SELECT $1, c.date,
+			COALESCE(SUM(c.balance) FILTER (WHERE c.type NOT IN ('cartao', 'emprestimo')), 0),
+			COALESCE(-SUM(c.balance) FILTER (WHERE c.type IN ('cartao', 'emprestimo')), 0),
+			SUM(c.balance)
+		FROM (
+			SELECT s.date, a.type,
+				to_base_currency($1, s.balance, a.currency, s.date) AS balance
+			FROM account_balance_snapshots s
+			JOIN accounts a ON a.id = s.account_id AND a.deleted_at IS NULL
+			WHERE s.user_id = $1 AND s.date >= $2::date
+			UNION ALL
+			SELECT d.date, a.type,
+				to_base_currency($1, v.value, a.currency, d.date)
+			FROM holdings h
+			JOIN accounts a ON a.id = h.account_id AND a.deleted_at IS NULL
+			CROSS JOIN (
+				SELECT generate_series($2::date, CURRENT_DATE, interval '1 day')::date AS date
+			) d
+			CROSS JOIN LATERAL (
+				SELECT holding_quantity(h.id, d.date) * COALESCE(asset_price($1, h.symbol, d.date), h.average_price) AS value
+			) v
+			WHERE h.user_id = $1
+			UNION ALL
+			SELECT d.date, 'emprestimo', -to_base_currency($1, loan_balance(l.id, d.date), l.currency, d.date)
+			FROM loans l
+			CROSS JOIN (
+				SELECT generate_series($2::date, CURRENT_DATE, interval '1 day')::date AS date
+			) d
+			WHERE l.user_id = $1
+		) c
+		GROUP BY c.date
+		ON CONFLICT (user_id, date) DO UPDATE
+			SET assets = EXCLUDED.assets, liabilities = EXCLUDED.liabilities, net_worth = EXCLUDED.net_worth
+	`, userID, from)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// staleNetWorthFrom descarta, na transação da movimentação, os snapshots de patrimônio
+// a partir do dia mais antigo entre dates cujo saldo mudou; ensureSnapshots os refaz
+// do último dia que sobrou até hoje na próxima consulta do histórico
+func staleNetWorthFrom(ctx context.Context, tx *sql.Tx, userID int, dates ...time.Time) error {
+	if len(dates) == 0 {
+		return nil
+	}
+	from := dates[0]
+	for _, d := range dates[1:] {
+		if d.Before(from) {
+			from = d
+		}
+	}
+	_, err := tx.ExecContext(ctx, `DELETE FROM net_worth_snapshots WHERE user_id = $1 AND date >= $2`, userID, from)
+	return err
+}
+
+// ensureSnapshots preenche os dias ainda sem snapshot no intervalo [from, hoje].
+// O último dia gravado e o dia de hoje são sempre recalculados.
+func ensureSnapshots(ctx context.Context, db *sql.DB, userID int, from time.Time) error {
+	var first, last sql.NullTime
+	err := db.QueryRowContext(ctx, `SELECT MIN(date), MAX(date) FROM net_worth_snapshots WHERE user_id = $1`, userID).Scan(&first, &last)
+	if err != nil {
+		return err
+	}
+
+	if !first.Valid || from.Before(first.Time) {
+		return refreshSnapshots(ctx, db, userID, from)
+	}
+	return refreshSnapshots(ctx, db, userID, last.Time)
+}
+
+// GetNetWorthHistory retorna a série temporal do patrimônio líquido
+// Parâmetros: from, to (YYYY-MM-DD), granularity (daily|monthly), accounts=true
+func (h *NetWorthHandler) GetNetWorthHistory(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := to.AddDate(-1, 0, 0)
+
+	if v := r.URL.Query().Get("from"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		parsed, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if from.After(to) {
+		http.Error(w, "Data inicial deve ser anterior à final", http.StatusBadRequest)
+		return
+	}
+
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = "monthly"
+	}
+	if granularity != "daily" && granularity != "monthly" {
+		http.Error(w, "Granularidade inválida, use daily ou monthly", http.StatusBadRequest)
+		return
+	}
+	withAccounts := r.URL.Query().Get("accounts") == "true"
+
+	if err := ensureSnapshots(r.Context(), h.DB, userID, from); err != nil {
+		writeConversionError(w, r, err, "Erro ao gerar snapshots de patrimônio")
+		return
+	}
+
+	baseCurrency, err := userBaseCurrency(r.Context(), h.DB, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar moeda base", http.StatusInternalServerError)
+		return
+	}
+
+	// Na visão mensal usa o último dia disponível de cada mês
+	query := `
+		SELECT date, assets, liabilities, net_worth
+		FROM net_worth_snapshots
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3
+		ORDER BY date`
+	if granularity == "monthly" {
+		query = `
+		SELECT date, assets, liabilities, net_worth FROM (
+			SELECT DISTINCT ON (date_trunc('month', date)) date, assets, liabilities, net_worth
+			FROM net_worth_snapshots
+			WHERE user_id = $1 AND date BETWEEN $2 AND $3
+			ORDER BY date_trunc('month', date), date DESC
+		) m
+		ORDER BY date`
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), query, userID, from, to)
+	if err != nil {
+		http.Error(w, "Erro ao buscar histórico de patrimônio", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar histórico de patrimônio", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	points := []models.NetWorthSnapshot{}
+	index := make(map[string]int)
+	for rows.Next() {
+		var p models.NetWorthSnapshot
+		if err := rows.Scan(&p.Date, &p.Assets, &p.Liabilities, &p.NetWorth); err != nil {
+			http.Error(w, "Erro ao ler histórico de patrimônio", http.StatusInternalServerError)
+			return
+		}
+		index[p.Date.Format("2006-01-02")] = len(points)
+		points = append(points, p)
+	}
+
+	if withAccounts && len(points) > 0 {
+		accRows, err := h.DB.QueryContext(r.Context(), `
+			SELECT s.date, s.account_id, a.name, a.type, a.currency, s.balance
+			FROM account_balance_snapshots s
+			JOIN accounts a ON a.id = s.account_id AND a.deleted_at IS NULL
+			WHERE s.user_id = $1 AND s.date BETWEEN $2 AND $3
+			ORDER BY s.date, a.name
+		`, userID, from, to)
+		if err != nil {
+			http.Error(w, "Erro ao buscar saldos das contas", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao buscar saldos das contas", "error", err)
+			return
+		}
+		defer accRows.Close()
+
+		for accRows.Next() {
+			var s models.AccountBalanceSnapshot
+			if err := accRows.Scan(&s.Date, &s.AccountID, &s.Name, &s.Type, &s.Currency, &s.Balance); err != nil {
+				http.Error(w, "Erro ao ler saldos das contas", http.StatusInternalServerError)
+				return
+			}
+			if i, ok := index[s.Date.Format("2006-01-02")]; ok {
+				points[i].Accounts = append(points[i].Accounts, s)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"granularity": granularity,
+		"currency":    baseCurrency,
+		"from":        from.Format("2006-01-02"),
+		"to":          to.Format("2006-01-02"),
+		"points":      points,
+	})
+}
+
+// RebuildSnapshots recalcula todos os snapshots do usuário a partir da primeira
+// movimentação registrada (útil após editar transações antigas)
+func (h *NetWorthHandler) RebuildSnapshots(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var start sql.NullTime
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT MIN(d) FROM (
+			SELECT MIN(date) AS d FROM incomes WHERE user_id = $1 AND deleted_at IS NULL
+			UNION ALL SELECT MIN(date) FROM expenses WHERE user_id = $1 AND deleted_at IS NULL
+			UNION ALL SELECT MIN(date) FROM transfers WHERE user_id = $1
+			UNION ALL SELECT MIN(created_at)::date FROM accounts WHERE user_id = $1 AND deleted_at IS NULL
+			UNION ALL SELECT MIN(start_date) FROM loans WHERE user_id = $1
+		) m
+	`, userID).Scan(&start)
+	if err != nil {
+		http.Error(w, "Erro ao buscar histórico", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar histórico", "error", err)
+		return
+	}
+	if !start.Valid {
+		start.Time = time.Now().UTC()
+	}
+
+	if err := refreshSnapshots(r.Context(), h.DB, userID, start.Time); err != nil {
+		writeConversionError(w, r, err, "Erro ao gerar snapshots de patrimônio")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Snapshots recalculados com sucesso",
+		"from":    start.Time.Format("2006-01-02"),
+	})
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// pageCursor aponta para a última linha entregue, na ordenação (date DESC, id DESC)
+type pageCursor struct {
+	Date time.Time
+	ID   int64
+}
+
+// pageParams são os parâmetros de paginação por cursor de uma listagem
+type pageParams struct {
+	Enabled bool // false devolve a lista completa (só com filtro de mês/ano)
+	Limit   int
+	After   *pageCursor
+}
+
+var errInvalidCursor = errors.New("cursor inválido")
+
+func encodeCursor(c pageCursor) string {
+	raw := c.Date.Format("2006-01-02") + "|" + strconv.FormatInt(c.ID, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (*pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	datePart, idPart, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return nil, errInvalidCursor
+	}
+	date, err := time.Parse("2006-01-02", datePart)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	id, err := strconv.ParseInt(idPart, 10, 64)
+	if err != nil {
+		return nil, errInvalidCursor
+	}
+	return &pageCursor{Date: date, ID: id}, nil
+}
+
+// parsePageParams lê limit e cursor. Sem filtro de mês/ano a listagem é sempre
+// paginada (defaultPageLimit), para não devolver o histórico inteiro de uma vez;
+// com o filtro e sem limit/cursor mantém a lista completa do período.
+func parsePageParams(r *http.Request) (pageParams, error) {
+	query := r.URL.Query()
+	p := pageParams{Limit: defaultPageLimit}
+
+	if v := query.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxPageLimit {
+			return p, errors.New("Parâmetro limit deve estar entre 1 e " + strconv.Itoa(maxPageLimit))
+		}
+		p.Limit = l
+		p.Enabled = true
+	}
+
+	if v := query.Get("cursor"); v != "" {
+		c, err := decodeCursor(v)
+		if err != nil {
+			return p, err
+		}
+		p.After = c
+		p.Enabled = true
+	}
+
+	if query.Get("month") == "" && query.Get("year") == "" {
+		p.Enabled = true
+	}
+
+	return p, nil
+}
+
+// keysetClause adiciona o filtro "depois do cursor", a ordenação estável e o limite
+// (um a mais, para saber se existe próxima página) à consulta
+func (p pageParams) keysetClause(args []interface{}) (string, []interface{}) {
+	clause := ""
+	if p.After != nil {
+		clause += " AND (date, id) < ($" + strconv.Itoa(len(args)+1) + ", $" + strconv.Itoa(len(args)+2) + ")"
+		args = append(args, p.After.Date, p.After.ID)
+	}
+	clause += " ORDER BY date DESC, id DESC"
+	if p.Enabled {
+		clause += " LIMIT $" + strconv.Itoa(len(args)+1)
+		args = append(args, p.Limit+1)
+	}
+	return clause, args
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strconv"
 
@@ -25,10 +26,10 @@ func GetUserPreferences(db *sql.DB) http.HandlerFunc {
 		}
 
 		var prefs models.UserPreferences
-		err := db.QueryRow(
-			"SELECT id, user_id, expenses_percent, entertainment_percent, investment_percent, created_at, updated_at FROM user_preferences WHERE user_id = $1",
+		err := db.QueryRowContext(r.Context(),
+			"SELECT id, user_id, expenses_percent, entertainment_percent, investment_percent, base_currency, created_at, updated_at FROM user_preferences WHERE user_id = $1",
 			userID,
-		).Scan(&prefs.ID, &prefs.UserID, &prefs.ExpensesPercent, &prefs.EntertainmentPercent, &prefs.InvestmentPercent, &prefs.CreatedAt, &prefs.UpdatedAt)
+		).Scan(&prefs.ID, &prefs.UserID, &prefs.ExpensesPercent, &prefs.EntertainmentPercent, &prefs.InvestmentPercent, &prefs.BaseCurrency, &prefs.CreatedAt, &prefs.UpdatedAt)
 
 		if err == sql.ErrNoRows {
 			// Se não existir, retorna os valores padrão
@@ -37,6 +38,7 @@ func GetUserPreferences(db *sql.DB) http.HandlerFunc {
 				ExpensesPercent:      50.0,
 				EntertainmentPercent: 30.0,
 				InvestmentPercent:    20.0,
+				BaseCurrency:         defaultCurrency,
 			}
 			w.Header().Set("Content-Type", "application/json")
 			json.NewEncoder(w).Encode(prefs)
@@ -71,6 +73,7 @@ func UpdateUserPreferences(db *sql.DB) http.HandlerFunc {
 			ExpensesPercent      float64 `json:"expenses_percent"`
 			EntertainmentPercent float64 `json:"entertainment_percent"`
 			InvestmentPercent    float64 `json:"investment_percent"`
+			BaseCurrency         *string `json:"base_currency"` // opcional, mantém a atual quando omitido
 		}
 
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -91,12 +94,49 @@ func UpdateUserPreferences(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		var baseCurrency *string
+		if req.BaseCurrency != nil {
+			code, ok := normalizeCurrency(*req.BaseCurrency)
+			if !ok {
+				http.Error(w, "Moeda inválida, use o código ISO de três letras (ex: BRL, USD)", http.StatusBadRequest)
+				return
+			}
+			baseCurrency = &code
+		}
+
+		previousCurrency, err := userBaseCurrency(r.Context(), db, userID)
+		if err != nil {
+			http.Error(w, "Erro ao buscar preferências", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := db.BeginTx(r.Context(), nil)
+		if err != nil {
+			http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		// Estado anterior para a auditoria (sem linha na primeira gravação)
+		var prefsID int64
+		err = tx.QueryRowContext(r.Context(), `SELECT id FROM user_preferences WHERE user_id = $1`, userID).Scan(&prefsID)
+		if err != nil && err != sql.ErrNoRows {
+			http.Error(w, "Erro ao buscar preferências", http.StatusInternalServerError)
+			return
+		}
+		before, err := auditSnapshot(r.Context(), tx, userID, "preferences", prefsID)
+		if err != nil {
+			http.Error(w, "Erro ao buscar preferências", http.StatusInternalServerError)
+			return
+		}
+
 		// Tenta fazer UPDATE, se não existir faz INSERT
-		result, err := db.Exec(
+		result, err := tx.ExecContext(r.Context(),
 			`UPDATE user_preferences 
-			 SET expenses_percent = $1, entertainment_percent = $2, investment_percent = $3, updated_at = NOW()
+			 SET expenses_percent = $1, entertainment_percent = $2, investment_percent = $3,
+			     base_currency = COALESCE($5, base_currency), updated_at = NOW()
 			 WHERE user_id = $4`,
-			req.ExpensesPercent, req.EntertainmentPercent, req.InvestmentPercent, userID,
+			req.ExpensesPercent, req.EntertainmentPercent, req.InvestmentPercent, userID, baseCurrency,
 		)
 
 		if err != nil {
@@ -111,17 +151,42 @@ func UpdateUserPreferences(db *sql.DB) http.HandlerFunc {
 		}
 
 		// Se não atualizou nada, faz INSERT
+		action := auditUpdate
 		if rowsAffected == 0 {
-			_, err := db.Exec(
-				`INSERT INTO user_preferences (user_id, expenses_percent, entertainment_percent, investment_percent)
-				 VALUES ($1, $2, $3, $4)`,
-				userID, req.ExpensesPercent, req.EntertainmentPercent, req.InvestmentPercent,
-			)
+			err := tx.QueryRowContext(r.Context(),
+				`INSERT INTO user_preferences (user_id, expenses_percent, entertainment_percent, investment_percent, base_currency)
+				 VALUES ($1, $2, $3, $4, COALESCE($5, 'BRL'))
+				 RETURNING id`,
+				userID, req.ExpensesPercent, req.EntertainmentPercent, req.InvestmentPercent, baseCurrency,
+			).Scan(&prefsID)
 
 			if err != nil {
 				http.Error(w, "Erro ao criar preferências", http.StatusInternalServerError)
 				return
 			}
+			action = auditCreate
+		}
+		if err := recordAudit(tx, r, userID, "preferences", prefsID, action, before); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+			http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+			return
+		}
+
+		// O patrimônio gravado está na moeda antiga: os snapshots são refeitos sob demanda
+		currentCurrency := previousCurrency
+		if baseCurrency != nil {
+			currentCurrency = *baseCurrency
+		}
+		if currentCurrency != previousCurrency {
+			if _, err := tx.ExecContext(r.Context(), `DELETE FROM net_worth_snapshots WHERE user_id = $1`, userID); err != nil {
+				http.Error(w, "Erro ao atualizar patrimônio", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -131,6 +196,7 @@ func UpdateUserPreferences(db *sql.DB) http.HandlerFunc {
 			"expenses_percent":      req.ExpensesPercent,
 			"entertainment_percent": req.EntertainmentPercent,
 			"investment_percent":    req.InvestmentPercent,
+			"base_currency":         currentCurrency,
 		})
 	}
 }
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+)
+
+// pathParam retorna o parâmetro do caminho nas rotas /v1 (ex.: {id} em /v1/expenses/{id})
+// ou, nas rotas antigas, o parâmetro de mesmo nome da query string
+func pathParam(r *http.Request, name string) string {
+	if v := r.PathValue(name); v != "" {
+		return v
+	}
+	return r.URL.Query().Get(name)
+}
+
+// Estado atual de cada recurso no formato do corpo do PUT, usado como base do PATCH
+const (
+	expensePatchSQL = `
+		SELECT json_build_object('description', description, 'amount', amount, 'category', category, 'group', "group",
+			'payment_method', payment_method, 'date', to_char(date, 'YYYY-MM-DD'), 'account_id', account_id,
+			'currency', currency, 'account_amount', account_amount)
+		FROM expenses WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	incomePatchSQL = `
+		SELECT json_build_object('description', description, 'amount', amount, 'date', to_char(date, 'YYYY-MM-DD'),
+			'account_id', account_id, 'currency', currency, 'account_amount', account_amount)
+		FROM incomes WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	accountPatchSQL = `
+		SELECT json_build_object('name', name, 'type', type, 'balance', balance, 'currency', currency,
+			'statement_closing_day', statement_closing_day, 'statement_due_day', statement_due_day,
+			'payment_account_id', payment_account_id)
+		FROM accounts WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	goalPatchSQL = `
+		SELECT json_build_object('name', name, 'target_amount', target_amount, 'current_amount', current_amount,
+			'deadline', to_char(deadline, 'YYYY-MM-DD'), 'yield_rate', yield_rate, 'account_id', account_id,
+			'reserved_percent', reserved_percent, 'contribution_amount', contribution_amount,
+			'contribution_day', contribution_day, 'contribution_account_id', contribution_account_id)
+		FROM goals WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`
+)
+
+type patchContextKey string
+
+// patchBaseKey guarda no PUT gerado pelo mergePatch o estado sobre o qual o patch foi aplicado
+const patchBaseKey patchContextKey = "patchBase"
+
+type patchBase struct {
+	currentSQL string
+	state      []byte
+}
+
+// mergePatch aplica o corpo do PATCH (JSON merge patch: campos ausentes ficam como
+// estão, null limpa o campo) sobre o estado atual lido por currentSQL e segue com
+// a atualização completa (update, o handler do PUT), que confere com checkPatchBase
+// se o registro não mudou nesse meio-tempo
+func mergePatch(w http.ResponseWriter, r *http.Request, db *sql.DB, currentSQL, notFound string, update http.HandlerFunc) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	id := pathParam(r, "id")
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		http.Error(w, "ID inválido", http.StatusBadRequest)
+		return
+	}
+
+	var current []byte
+	err := db.QueryRowContext(r.Context(), currentSQL, id, userID).Scan(&current)
+	if err == sql.ErrNoRows {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao buscar registro", http.StatusInternalServerError)
+		return
+	}
+
+	var merged, patch map[string]json.RawMessage
+	if err := json.Unmarshal(current, &merged); err != nil {
+		http.Error(w, "Erro ao buscar registro", http.StatusInternalServerError)
+		return
+	}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	for field, value := range patch {
+		merged[field] = value
+	}
+
+	body, err := json.Marshal(merged)
+	if err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	put := r.Clone(context.WithValue(r.Context(), patchBaseKey, patchBase{currentSQL: currentSQL, state: current}))
+	put.Method = http.MethodPut
+	put.Body = io.NopCloser(bytes.NewReader(body))
+	put.ContentLength = int64(len(body))
+	update(w, put)
+}
+
+// checkPatchBase, quando o PUT veio de um PATCH, trava o registro na transação do
+// PUT e confere se ele ainda está no estado lido pelo mergePatch; se outra
+// requisição o alterou no meio-tempo, o patch seria aplicado sobre dados antigos
+// e responde 409. Retorna status 0 quando a atualização pode seguir.
+func checkPatchBase(ctx context.Context, tx *sql.Tx, id string, userID int) (int, string) {
+	base, ok := ctx.Value(patchBaseKey).(patchBase)
+	if !ok {
+		return 0, ""
+	}
+
+	var state []byte
+	err := tx.QueryRowContext(ctx, base.currentSQL+` FOR UPDATE`, id, userID).Scan(&state)
+	if err == sql.ErrNoRows {
+		return http.StatusNotFound, "Registro não encontrado"
+	}
+	if err != nil {
+		return http.StatusInternalServerError, "Erro ao buscar registro"
+	}
+	if !bytes.Equal(state, base.state) {
+		return http.StatusConflict, "Registro alterado por outra requisição, tente novamente"
+	}
+	return 0, ""
+}
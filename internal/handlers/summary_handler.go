@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log/slog"
+	"math"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
@@ -28,6 +32,108 @@ type Summary struct {
 	RealInvest      float64 `json:"real_invest"`
 	SaldoRestante   float64 `json:"saldo_restante"`
 	PatrimonioTotal float64 `json:"patrimonio_total"`
+	De              string  `json:"de"`
+	Ate             string  `json:"ate"`
+	MoedaBase       string  `json:"moeda_base"` // todos os valores convertidos para esta moeda
+
+	Comparacao *SummaryComparison `json:"comparacao,omitempty"`
+}
+
+// SummaryComparison compara o período com o mesmo período do ano anterior
+type SummaryComparison struct {
+	De         string `json:"de"`
+	Ate        string `json:"ate"`
+	RendaTotal Delta  `json:"renda_total"`
+	GastoTotal Delta  `json:"gasto_total"`
+	RealFixos  Delta  `json:"real_fixos"`
+	RealLazer  Delta  `json:"real_lazer"`
+	RealInvest Delta  `json:"real_invest"`
+}
+
+// Delta é a variação de um valor entre o período atual e o de comparação
+type Delta struct {
+	Current       float64  `json:"current"`
+	Previous      float64  `json:"previous"`
+	Change        float64  `json:"change"`
+	ChangePercent *float64 `json:"change_percent"` // nil quando o valor anterior é zero
+}
+
+func newDelta(current, previous float64) Delta {
+	d := Delta{Current: current, Previous: previous, Change: roundMoney(current - previous)}
+	if previous != 0 {
+		pct := math.Round((current-previous)/math.Abs(previous)*10000) / 100
+		d.ChangePercent = &pct
+	}
+	return d
+}
+
+// periodRange é um intervalo de datas inclusivo
+type periodRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// parsePeriodRange lê from/to (YYYY-MM-DD) ou, na ausência deles, month/year
+// (padrão: mês atual)
+func parsePeriodRange(r *http.Request, now time.Time) (periodRange, error) {
+	fromParam := r.URL.Query().Get("from")
+	toParam := r.URL.Query().Get("to")
+
+	if fromParam != "" || toParam != "" {
+		if fromParam == "" {
+			return periodRange{}, fmt.Errorf("Parâmetro from é obrigatório quando to é informado")
+		}
+		from, err := time.Parse("2006-01-02", fromParam)
+		if err != nil {
+			return periodRange{}, fmt.Errorf("Data inválida, use YYYY-MM-DD")
+		}
+		to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if toParam != "" {
+			to, err = time.Parse("2006-01-02", toParam)
+			if err != nil {
+				return periodRange{}, fmt.Errorf("Data inválida, use YYYY-MM-DD")
+			}
+		}
+		if from.After(to) {
+			return periodRange{}, fmt.Errorf("Data inicial deve ser anterior à final")
+		}
+		return periodRange{From: from, To: to}, nil
+	}
+
+	month := int(now.Month())
+	year := now.Year()
+
+	if monthParam := r.URL.Query().Get("month"); monthParam != "" {
+		if m, err := strconv.Atoi(monthParam); err == nil {
+			month = m
+		}
+	}
+
+	if yearParam := r.URL.Query().Get("year"); yearParam != "" {
+		if y, err := strconv.Atoi(yearParam); err == nil {
+			year = y
+		}
+	}
+
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return periodRange{From: from, To: from.AddDate(0, 1, -1)}, nil
+}
+
+// previousYear retorna o mesmo período no ano anterior, preservando fim de mês
+// (ex: 29/02 vira 28/02)
+func (p periodRange) previousYear() periodRange {
+	shift := func(t time.Time) time.Time {
+		return dateInMonth(t.Year()-1, t.Month(), t.Day())
+	}
+	to := shift(p.To)
+	if p.To.AddDate(0, 0, 1).Day() == 1 {
+		to = dateInMonth(p.To.Year()-1, p.To.Month(), 31)
+	}
+	return periodRange{From: shift(p.From), To: to}
+}
+
+func compareRequested(r *http.Request) bool {
+	return r.URL.Query().Get("compare") == "yoy"
 }
 
 type MonthlyData struct {
@@ -37,139 +143,233 @@ type MonthlyData struct {
 	Expenses float64 `json:"expenses"`
 	Balance  float64 `json:"balance"`
 	MonthNum int     `json:"month_num"`
+	Period   string  `json:"period"` // 2025-01, 2025-Q1 ou 2025
+
+	Comparison *MonthlyComparison `json:"comparison,omitempty"`
+}
+
+type MonthlyComparison struct {
+	Income   Delta `json:"income"`
+	Expenses Delta `json:"expenses"`
+	Balance  Delta `json:"balance"`
+}
+
+// historyGranularities mapeia a granularidade para o passo em meses e o argumento de date_trunc
+var historyGranularities = map[string]struct {
+	months int
+	trunc  string
+}{
+	"monthly":   {1, "month"},
+	"quarterly": {3, "quarter"},
+	"yearly":    {12, "year"},
+}
+
+// truncatePeriod alinha a data ao início do período da granularidade
+func truncatePeriod(t time.Time, months int) time.Time {
+	month := time.Month((int(t.Month())-1)/months*months + 1)
+	return time.Date(t.Year(), month, 1, 0, 0, 0, 0, time.UTC)
 }
 
+func periodLabel(start time.Time, granularity string) (string, string) {
+	switch granularity {
+	case "quarterly":
+		q := (int(start.Month())-1)/3 + 1
+		return "Q" + strconv.Itoa(q), fmt.Sprintf("%d-Q%d", start.Year(), q)
+	case "yearly":
+		return strconv.Itoa(start.Year()), strconv.Itoa(start.Year())
+	default:
+		return start.Format("Jan"), start.Format("2006-01")
+	}
+}
+
+// sumByPeriod soma a coluna amount da tabela (na moeda base) por período (date_trunc) no intervalo
+func (h *SummaryHandler) sumByPeriod(ctx context.Context, table string, userID int, p periodRange, trunc string) (map[string]float64, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date_trunc($4, date)::date AS bucket, COALESCE(SUM(`+baseAmountSQL("$1")+`), 0)
+		FROM `+table+`
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3 AND deleted_at IS NULL
+		GROUP BY bucket
+	`, userID, p.From, p.To, trunc)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[string]float64)
+	for rows.Next() {
+		var bucket time.Time
+		var amount float64
+		if err := rows.Scan(&bucket, &amount); err != nil {
+			return nil, err
+		}
+		totals[bucket.Format("2006-01-02")] = amount
+	}
+	return totals, rows.Err()
+}
+
+// GetMonthlyHistory retorna renda e gastos por período
+// Parâmetros: from, to (YYYY-MM-DD, padrão: últimos 12 meses), granularity (monthly|quarterly|yearly), compare=yoy
 func (h *SummaryHandler) GetMonthlyHistory(w http.ResponseWriter, r *http.Request) {
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	// Get last 12 months
-	var monthlyData []MonthlyData
-	now := time.Now()
+	granularity := r.URL.Query().Get("granularity")
+	if granularity == "" {
+		granularity = "monthly"
+	}
+	g, ok := historyGranularities[granularity]
+	if !ok {
+		http.Error(w, "Granularidade inválida, use monthly, quarterly ou yearly", http.StatusBadRequest)
+		return
+	}
 
-	for i := 11; i >= 0; i-- {
-		currentMonth := now.AddDate(0, -i, 0)
-		month := int(currentMonth.Month())
-		year := currentMonth.Year()
+	// Padrão: últimos 12 meses
+	now := time.Now()
+	period := periodRange{
+		From: time.Date(now.Year(), now.Month()-11, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, time.UTC),
+	}
+	if r.URL.Query().Get("from") != "" || r.URL.Query().Get("to") != "" {
+		p, err := parsePeriodRange(r, now)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		period = p
+	}
 
-		var income, expenses float64
+	incomes, err := h.sumByPeriod(r.Context(), "incomes", userID, period, g.trunc)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao calcular renda")
+		return
+	}
+	expenses, err := h.sumByPeriod(r.Context(), "expenses", userID, period, g.trunc)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao calcular gastos")
+		return
+	}
 
-		h.DB.QueryRow(`
-			SELECT COALESCE(SUM(amount), 0)
-			FROM incomes
-			WHERE EXTRACT(MONTH FROM date) = $1
-			AND EXTRACT(YEAR FROM date) = $2
-			AND user_id = $3
-		`, month, year, userID).Scan(&income)
+	compare := compareRequested(r)
+	var prevIncomes, prevExpenses map[string]float64
+	if compare {
+		prev := period.previousYear()
+		if prevIncomes, err = h.sumByPeriod(r.Context(), "incomes", userID, prev, g.trunc); err == nil {
+			prevExpenses, err = h.sumByPeriod(r.Context(), "expenses", userID, prev, g.trunc)
+		}
+		if err != nil {
+			writeConversionError(w, r, err, "Erro ao calcular período anterior")
+			return
+		}
+	}
 
-		h.DB.QueryRow(`
-			SELECT COALESCE(SUM(amount), 0)
-			FROM expenses
-			WHERE EXTRACT(MONTH FROM date) = $1
-			AND EXTRACT(YEAR FROM date) = $2
-			AND user_id = $3
-		`, month, year, userID).Scan(&expenses)
+	monthlyData := []MonthlyData{}
+	for start := truncatePeriod(period.From, g.months); !start.After(period.To); start = start.AddDate(0, g.months, 0) {
+		key := start.Format("2006-01-02")
+		income, expense := incomes[key], expenses[key]
+		label, periodKey := periodLabel(start, granularity)
 
-		monthlyData = append(monthlyData, MonthlyData{
-			Month:    currentMonth.Format("Jan"),
-			Year:     year,
+		data := MonthlyData{
+			Month:    label,
+			Year:     start.Year(),
 			Income:   income,
-			Expenses: expenses,
-			Balance:  income - expenses,
-			MonthNum: month,
-		})
+			Expenses: expense,
+			Balance:  income - expense,
+			MonthNum: int(start.Month()),
+			Period:   periodKey,
+		}
+		if compare {
+			prevKey := start.AddDate(-1, 0, 0).Format("2006-01-02")
+			prevIncome, prevExpense := prevIncomes[prevKey], prevExpenses[prevKey]
+			data.Comparison = &MonthlyComparison{
+				Income:   newDelta(income, prevIncome),
+				Expenses: newDelta(expense, prevExpense),
+				Balance:  newDelta(income-expense, prevIncome-prevExpense),
+			}
+		}
+		monthlyData = append(monthlyData, data)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(monthlyData)
 }
 
-func (h *SummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
-	now := time.Now()
-	monthParam := r.URL.Query().Get("month")
-	yearParam := r.URL.Query().Get("year")
-	userIDVal := r.Context().Value(middleware.UserIDKey)
-	userID, _ := userIDVal.(int)
+// periodTotals são os totais de renda e gasto de um período, com gastos por grupo (50/30/20)
+type periodTotals struct {
+	Income     float64
+	Expenses   float64
+	RealFixos  float64
+	RealLazer  float64
+	RealInvest float64
+}
 
-	month := int(now.Month())
-	year := now.Year()
+func (h *SummaryHandler) loadPeriodTotals(ctx context.Context, userID int, p periodRange) (periodTotals, error) {
+	var t periodTotals
 
-	if monthParam != "" {
-		if m, err := strconv.Atoi(monthParam); err == nil {
-			month = m
-		}
+	err := h.DB.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(`+baseAmountSQL("$3")+`), 0)
+		FROM incomes
+		WHERE date BETWEEN $1 AND $2 AND user_id = $3 AND deleted_at IS NULL
+	`, p.From, p.To, userID).Scan(&t.Income)
+	if err != nil {
+		return t, err
 	}
 
-	if yearParam != "" {
-		if y, err := strconv.Atoi(yearParam); err == nil {
-			year = y
+	// 🔹 Busca gastos por grupo (50/30/20) usando o campo group
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT "group", COALESCE(SUM(`+baseAmountSQL("$3")+`), 0)
+		FROM `+expenseLines+` expenses
+		WHERE date BETWEEN $1 AND $2
+			AND user_id = $3
+		GROUP BY "group"
+	`, p.From, p.To, userID)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var group string
+		var amount float64
+		if err := rows.Scan(&group, &amount); err != nil {
+			return t, err
+		}
+		t.Expenses += amount
+		switch group {
+		case "essencial":
+			t.RealFixos = amount
+		case "lazer":
+			t.RealLazer = amount
+		case "investimento":
+			t.RealInvest = amount
 		}
 	}
+	return t, rows.Err()
+}
 
-	var totalIncome float64
-	err := h.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0) 
-		FROM incomes
-		WHERE month = $1 AND year = $2 AND user_id = $3
-	`, month, year, userID).Scan(&totalIncome)
+// GetSummary retorna o resumo financeiro do período com a regra 50/30/20
+// Parâmetros: month/year (padrão: mês atual) ou from/to (YYYY-MM-DD), compare=yoy
+func (h *SummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	period, err := parsePeriodRange(r, now)
 	if err != nil {
-		http.Error(w, "Erro ao calcular renda", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var totalExpenses float64
-	err = h.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0)
-		FROM expenses
-		WHERE EXTRACT(MONTH FROM date) = $1
-			AND EXTRACT(YEAR FROM date) = $2
-			AND user_id = $3
-	`, month, year, userID).Scan(&totalExpenses)
+	totals, err := h.loadPeriodTotals(r.Context(), userID, period)
 	if err != nil {
-		http.Error(w, "Erro ao calcular gastos", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
+		writeConversionError(w, r, err, "Erro ao calcular resumo")
 		return
 	}
 
-	// 🔹 Busca gastos por grupo (50/30/20) usando o campo group
-	var realFixos, realLazer, realInvest float64
-
-	// Essenciais (50%)
-	h.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0)
-		FROM expenses
-		WHERE "group" = 'essencial'
-		AND EXTRACT(MONTH FROM date) = $1
-		AND EXTRACT(YEAR FROM date) = $2
-		AND user_id = $3
-	`, month, year, userID).Scan(&realFixos)
-
-	// Lazer (30%)
-	h.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0)
-		FROM expenses
-		WHERE "group" = 'lazer'
-		AND EXTRACT(MONTH FROM date) = $1
-		AND EXTRACT(YEAR FROM date) = $2
-		AND user_id = $3
-	`, month, year, userID).Scan(&realLazer)
-
-	// Investimento (20%)
-	h.DB.QueryRow(`
-		SELECT COALESCE(SUM(amount), 0)
-		FROM expenses
-		WHERE "group" = 'investimento'
-		AND EXTRACT(MONTH FROM date) = $1
-		AND EXTRACT(YEAR FROM date) = $2
-		AND user_id = $3
-	`, month, year, userID).Scan(&realInvest)
-
 	// Buscar preferências do usuário
 	var expensesPercent, entertainmentPercent, investmentPercent float64
 	expensesPercent, entertainmentPercent, investmentPercent = 50, 30, 20 // padrão
 
-	err = h.DB.QueryRow(`
+	err = h.DB.QueryRowContext(r.Context(), `
 		SELECT expenses_percent, entertainment_percent, investment_percent
 		FROM user_preferences
 		WHERE user_id = $1
@@ -177,44 +377,77 @@ func (h *SummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
 
 	// Se não encontrar preferências, usa valores padrão (já definidos acima)
 	if err != nil && err != sql.ErrNoRows {
-		fmt.Println("Erro ao buscar preferências:", err)
+		slog.ErrorContext(r.Context(), "Erro ao buscar preferências", "error", err)
 	}
 
-	// Calcular patrimônio total (soma de TODAS as contas)
+	// Calcular patrimônio total (soma de TODAS as contas e das posições em investimentos,
+	// menos o saldo devedor dos empréstimos, na moeda base)
 	var patrimonioTotal float64
-	err = h.DB.QueryRow(`
-		SELECT COALESCE(SUM(balance), 0)
-		FROM accounts
-		WHERE user_id = $1
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT COALESCE(SUM(`+baseBalanceSQL("$1")+`), 0) + (`+holdingsValueSQL+`) - (`+loansBalanceSQL+`)
+		FROM accounts a
+		WHERE user_id = $1 AND deleted_at IS NULL
 	`, userID).Scan(&patrimonioTotal)
+	if missing, ok := missingRateMessage(err); ok {
+		http.Error(w, missing, http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
-		fmt.Println("Erro ao calcular patrimônio total:", err)
+		slog.ErrorContext(r.Context(), "Erro ao calcular patrimônio total", "error", err)
 	}
 
 	// Calcular saldo restante (apenas contas corrente e cartao)
 	var saldoRestante float64
-	err = h.DB.QueryRow(`
-		SELECT COALESCE(SUM(balance), 0)
-		FROM accounts
-		WHERE user_id = $1 AND type IN ('corrente', 'cartao')
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT COALESCE(SUM(`+baseBalanceSQL("$1")+`), 0)
+		FROM accounts a
+		WHERE user_id = $1 AND type IN ('corrente', 'cartao') AND deleted_at IS NULL
 	`, userID).Scan(&saldoRestante)
+	if missing, ok := missingRateMessage(err); ok {
+		http.Error(w, missing, http.StatusUnprocessableEntity)
+		return
+	}
 	if err != nil {
-		fmt.Println("Erro ao calcular saldo restante:", err)
+		slog.ErrorContext(r.Context(), "Erro ao calcular saldo restante", "error", err)
 	}
 
 	summary := Summary{
 		Mes:             now.Month().String(),
-		Ano:             year,
-		RendaTotal:      totalIncome,
-		GastoTotal:      totalExpenses,
-		IdealFixos:      totalIncome * (expensesPercent / 100),
-		IdealLazer:      totalIncome * (entertainmentPercent / 100),
-		IdealInvest:     totalIncome * (investmentPercent / 100),
-		RealFixos:       realFixos,
-		RealLazer:       realLazer,
-		RealInvest:      realInvest,
+		Ano:             period.From.Year(),
+		RendaTotal:      totals.Income,
+		GastoTotal:      totals.Expenses,
+		IdealFixos:      totals.Income * (expensesPercent / 100),
+		IdealLazer:      totals.Income * (entertainmentPercent / 100),
+		IdealInvest:     totals.Income * (investmentPercent / 100),
+		RealFixos:       totals.RealFixos,
+		RealLazer:       totals.RealLazer,
+		RealInvest:      totals.RealInvest,
 		SaldoRestante:   saldoRestante,
 		PatrimonioTotal: patrimonioTotal,
+		De:              period.From.Format("2006-01-02"),
+		Ate:             period.To.Format("2006-01-02"),
+		MoedaBase:       defaultCurrency,
+	}
+	if baseCurrency, err := userBaseCurrency(r.Context(), h.DB, userID); err == nil {
+		summary.MoedaBase = baseCurrency
+	}
+
+	if compareRequested(r) {
+		prev := period.previousYear()
+		prevTotals, err := h.loadPeriodTotals(r.Context(), userID, prev)
+		if err != nil {
+			writeConversionError(w, r, err, "Erro ao calcular período anterior")
+			return
+		}
+		summary.Comparacao = &SummaryComparison{
+			De:         prev.From.Format("2006-01-02"),
+			Ate:        prev.To.Format("2006-01-02"),
+			RendaTotal: newDelta(totals.Income, prevTotals.Income),
+			GastoTotal: newDelta(totals.Expenses, prevTotals.Expenses),
+			RealFixos:  newDelta(totals.RealFixos, prevTotals.RealFixos),
+			RealLazer:  newDelta(totals.RealLazer, prevTotals.RealLazer),
+			RealInvest: newDelta(totals.RealInvest, prevTotals.RealInvest),
+		}
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -224,82 +457,246 @@ func (h *SummaryHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
 type CategoryBreakdown struct {
 	Category string  `json:"category"`
 	Amount   float64 `json:"amount"`
+
+	Comparison *Delta `json:"comparison,omitempty"`
 }
 
 type GroupBreakdown struct {
 	Group      string              `json:"group"`
 	Total      float64             `json:"total"`
 	Categories []CategoryBreakdown `json:"categories"`
+
+	Comparison *Delta `json:"comparison,omitempty"`
 }
 
+// loadBreakdown agrupa os gastos do período por grupo e categoria, ordenados por grupo
+func (h *SummaryHandler) loadBreakdown(ctx context.Context, userID int, p periodRange) ([]GroupBreakdown, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT "group", category, COALESCE(SUM(`+baseAmountSQL("$3")+`), 0) as total
+		FROM `+expenseLines+` expenses
+		WHERE date BETWEEN $1 AND $2
+		AND user_id = $3
+		GROUP BY "group", category
+		ORDER BY "group", total DESC
+	`, p.From, p.To, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []GroupBreakdown{}
+	for rows.Next() {
+		var group, category string
+		var amount float64
+
+		if err := rows.Scan(&group, &category, &amount); err != nil {
+			continue
+		}
+
+		if len(result) == 0 || result[len(result)-1].Group != group {
+			result = append(result, GroupBreakdown{
+				Group:      group,
+				Total:      0,
+				Categories: []CategoryBreakdown{},
+			})
+		}
+
+		gb := &result[len(result)-1]
+		gb.Total += amount
+		gb.Categories = append(gb.Categories, CategoryBreakdown{
+			Category: category,
+			Amount:   amount,
+		})
+	}
+	return result, rows.Err()
+}
+
+// compareBreakdown adiciona a variação contra o período anterior em cada grupo e
+// categoria; itens que só existiam no período anterior entram com valor zero
+func compareBreakdown(current, previous []GroupBreakdown) []GroupBreakdown {
+	prevGroups := make(map[string]GroupBreakdown)
+	for _, gb := range previous {
+		prevGroups[gb.Group] = gb
+	}
+
+	seenGroups := make(map[string]bool)
+	for i := range current {
+		gb := &current[i]
+		seenGroups[gb.Group] = true
+		prev := prevGroups[gb.Group]
+
+		prevCategories := make(map[string]float64)
+		for _, cb := range prev.Categories {
+			prevCategories[cb.Category] = cb.Amount
+		}
+		seenCategories := make(map[string]bool)
+		for j := range gb.Categories {
+			cb := &gb.Categories[j]
+			seenCategories[cb.Category] = true
+			d := newDelta(cb.Amount, prevCategories[cb.Category])
+			cb.Comparison = &d
+		}
+		for _, cb := range prev.Categories {
+			if !seenCategories[cb.Category] {
+				d := newDelta(0, cb.Amount)
+				gb.Categories = append(gb.Categories, CategoryBreakdown{Category: cb.Category, Comparison: &d})
+			}
+		}
+
+		d := newDelta(gb.Total, prev.Total)
+		gb.Comparison = &d
+	}
+
+	for _, prev := range previous {
+		if seenGroups[prev.Group] {
+			continue
+		}
+		gb := GroupBreakdown{Group: prev.Group, Categories: []CategoryBreakdown{}}
+		for _, cb := range prev.Categories {
+			d := newDelta(0, cb.Amount)
+			gb.Categories = append(gb.Categories, CategoryBreakdown{Category: cb.Category, Comparison: &d})
+		}
+		d := newDelta(0, prev.Total)
+		gb.Comparison = &d
+		current = append(current, gb)
+	}
+
+	return current
+}
+
+// GetExpenseBreakdown retorna os gastos do período por grupo e categoria
+// Parâmetros: month/year (padrão: mês atual) ou from/to (YYYY-MM-DD), compare=yoy
 func (h *SummaryHandler) GetExpenseBreakdown(w http.ResponseWriter, r *http.Request) {
 	now := time.Now()
-	monthParam := r.URL.Query().Get("month")
-	yearParam := r.URL.Query().Get("year")
 	userIDVal := r.Context().Value(middleware.UserIDKey)
 	userID, _ := userIDVal.(int)
 
-	month := int(now.Month())
-	year := now.Year()
+	period, err := parsePeriodRange(r, now)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	if monthParam != "" {
-		if m, err := strconv.Atoi(monthParam); err == nil {
-			month = m
-		}
+	result, err := h.loadBreakdown(r.Context(), userID, period)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao buscar breakdown")
+		return
 	}
 
-	if yearParam != "" {
-		if y, err := strconv.Atoi(yearParam); err == nil {
-			year = y
+	if compareRequested(r) {
+		previous, err := h.loadBreakdown(r.Context(), userID, period.previousYear())
+		if err != nil {
+			writeConversionError(w, r, err, "Erro ao buscar breakdown do período anterior")
+			return
 		}
+		result = compareBreakdown(result, previous)
 	}
 
-	rows, err := h.DB.Query(`
-		SELECT "group", category, COALESCE(SUM(amount), 0) as total
-		FROM expenses
-		WHERE EXTRACT(MONTH FROM date) = $1
-		AND EXTRACT(YEAR FROM date) = $2
-		AND user_id = $3
-		GROUP BY "group", category
-		ORDER BY "group", total DESC
-	`, month, year, userID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+type TagBreakdown struct {
+	Tag          string              `json:"tag"`
+	Expenses     float64             `json:"expenses"`
+	ExpenseCount int                 `json:"expense_count"`
+	Incomes      float64             `json:"incomes"`
+	IncomeCount  int                 `json:"income_count"`
+	Categories   []CategoryBreakdown `json:"categories"` // gastos da tag por categoria
+}
+
+// GetTagBreakdown retorna gastos e rendas do período agrupados por tag
+// Parâmetros: month/year (padrão: mês atual) ou from/to (YYYY-MM-DD)
+func (h *SummaryHandler) GetTagBreakdown(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	period, err := parsePeriodRange(r, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT t.name, e.category, COALESCE(SUM(to_base_currency($1, e.amount, e.currency, e.date)), 0),
+			-- gastos distintos da tag no período (um gasto dividido gera várias linhas)
+			(SELECT COUNT(*) FROM expense_tags et2 JOIN expenses e2 ON e2.id = et2.expense_id
+			 WHERE et2.tag_id = t.id AND e2.date BETWEEN $2 AND $3 AND e2.deleted_at IS NULL)
+		FROM expense_tags et
+		JOIN tags t ON t.id = et.tag_id
+		JOIN `+expenseLines+` e ON e.id = et.expense_id
+		WHERE t.user_id = $1 AND e.user_id = $1 AND e.date BETWEEN $2 AND $3
+		GROUP BY t.id, t.name, e.category
+		ORDER BY t.name, 3 DESC
+	`, userID, period.From, period.To)
 	if err != nil {
-		http.Error(w, "Erro ao buscar breakdown", http.StatusInternalServerError)
-		fmt.Println("Erro:", err)
+		writeConversionError(w, r, err, "Erro ao buscar breakdown por tag")
 		return
 	}
 	defer rows.Close()
 
-	groupMap := make(map[string]*GroupBreakdown)
+	tagMap := make(map[string]*TagBreakdown)
+	var order []string
+	get := func(name string) *TagBreakdown {
+		if tagMap[name] == nil {
+			tagMap[name] = &TagBreakdown{Tag: name, Categories: []CategoryBreakdown{}}
+			order = append(order, name)
+		}
+		return tagMap[name]
+	}
 
 	for rows.Next() {
-		var group, category string
+		var name, category string
 		var amount float64
-
-		if err := rows.Scan(&group, &category, &amount); err != nil {
+		var count int
+		if err := rows.Scan(&name, &category, &amount, &count); err != nil {
 			continue
 		}
+		tb := get(name)
+		tb.Expenses += amount
+		tb.ExpenseCount = count
+		tb.Categories = append(tb.Categories, CategoryBreakdown{Category: category, Amount: amount})
+	}
+	if err := rows.Err(); err != nil {
+		writeConversionError(w, r, err, "Erro ao buscar breakdown por tag")
+		return
+	}
 
-		if groupMap[group] == nil {
-			groupMap[group] = &GroupBreakdown{
-				Group:      group,
-				Total:      0,
-				Categories: []CategoryBreakdown{},
-			}
-		}
+	incomeRows, err := h.DB.QueryContext(r.Context(), `
+		SELECT t.name, COALESCE(SUM(to_base_currency($1, i.amount, i.currency, i.date)), 0), COUNT(*)
+		FROM income_tags it
+		JOIN tags t ON t.id = it.tag_id
+		JOIN incomes i ON i.id = it.income_id
+		WHERE t.user_id = $1 AND i.user_id = $1 AND i.date BETWEEN $2 AND $3 AND i.deleted_at IS NULL
+		GROUP BY t.name
+	`, userID, period.From, period.To)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao buscar breakdown por tag")
+		return
+	}
+	defer incomeRows.Close()
 
-		groupMap[group].Total += amount
-		groupMap[group].Categories = append(groupMap[group].Categories, CategoryBreakdown{
-			Category: category,
-			Amount:   amount,
-		})
+	for incomeRows.Next() {
+		var name string
+		var amount float64
+		var count int
+		if err := incomeRows.Scan(&name, &amount, &count); err != nil {
+			continue
+		}
+		tb := get(name)
+		tb.Incomes = amount
+		tb.IncomeCount = count
+	}
+	if err := incomeRows.Err(); err != nil {
+		writeConversionError(w, r, err, "Erro ao buscar breakdown por tag")
+		return
 	}
 
-	result := []GroupBreakdown{}
-	for _, gb := range groupMap {
-		result = append(result, *gb)
+	result := []TagBreakdown{}
+	for _, name := range order {
+		result = append(result, *tagMap[name])
 	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Expenses > result[j].Expenses })
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(result)
@@ -0,0 +1,309 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
+)
+
+type TagHandler struct {
+	DB *sql.DB
+}
+
+const maxTagLength = 50
+
+// tagLinks mapeia o tipo de transação para sua tabela de ligação com tags
+var tagLinks = map[string]struct {
+	table  string
+	column string
+}{
+	"expense":  {"expense_tags", "expense_id"},
+	"income":   {"income_tags", "income_id"},
+	"transfer": {"transfer_tags", "transfer_id"},
+}
+
+// normalizeTags remove espaços, vazios e duplicados (sem diferenciar maiúsculas)
+func normalizeTags(names []string) []string {
+	seen := make(map[string]bool)
+	tags := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if len([]rune(name)) > maxTagLength {
+			name = string([]rune(name)[:maxTagLength])
+		}
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		tags = append(tags, name)
+	}
+	return tags
+}
+
+// parseTagFilter lê o parâmetro tag (separado por vírgula) em minúsculas
+func parseTagFilter(r *http.Request) []string {
+	param := r.URL.Query().Get("tag")
+	if param == "" {
+		return nil
+	}
+	tags := normalizeTags(strings.Split(param, ","))
+	for i := range tags {
+		tags[i] = strings.ToLower(tags[i])
+	}
+	return tags
+}
+
+// tagSubquery retorna a subconsulta com os ids das transações que possuem alguma
+// das tags do parâmetro $argPos; o usuário deve ser o parâmetro $1 da consulta
+func tagSubquery(kind string, argPos int) string {
+	link := tagLinks[kind]
+	return "SELECT lt." + link.column + " FROM " + link.table + " lt JOIN tags t ON t.id = lt.tag_id" +
+		" WHERE t.user_id = $1 AND LOWER(t.name) = ANY($" + strconv.Itoa(argPos) + ")"
+}
+
+// tagFilterClause retorna o filtro SQL "possui alguma das tags" para a coluna id da transação
+func tagFilterClause(kind, idColumn string, argPos int) string {
+	return " AND " + idColumn + " IN (" + tagSubquery(kind, argPos) + ")"
+}
+
+// setTransactionTags substitui as tags de uma transação, criando as que ainda não existem
+func setTransactionTags(ctx context.Context, tx *sql.Tx, userID int, kind string, id int64, names []string) error {
+	link := tagLinks[kind]
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+link.table+` WHERE `+link.column+` = $1`, id); err != nil {
+		return err
+	}
+
+	for _, name := range normalizeTags(names) {
+		var tagID int64
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO tags (user_id, name) VALUES ($1, $2)
+			ON CONFLICT (user_id, (LOWER(name))) DO UPDATE SET name = tags.name
+			RETURNING id
+		`, userID, name).Scan(&tagID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, `INSERT INTO `+link.table+` (`+link.column+`, tag_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, id, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTransactionTags busca as tags de várias transações de uma vez
+func loadTransactionTags(ctx context.Context, db *sql.DB, userID int, kind string, ids []int64) (map[int64][]string, error) {
+	tags := make(map[int64][]string)
+	if len(ids) == 0 {
+		return tags, nil
+	}
+
+	link := tagLinks[kind]
+	rows, err := db.QueryContext(ctx, `
+		SELECT lt.`+link.column+`, t.name
+		FROM `+link.table+` lt
+		JOIN tags t ON t.id = lt.tag_id
+		WHERE t.user_id = $1 AND lt.`+link.column+` = ANY($2)
+		ORDER BY t.name
+	`, userID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		tags[id] = append(tags[id], name)
+	}
+	return tags, rows.Err()
+}
+
+func (h *TagHandler) GetTags(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	rows, err := h.DB.QueryContext(r.Context(), `SELECT id, name, created_at FROM tags WHERE user_id = $1 ORDER BY LOWER(name)`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tags", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	tags := []models.Tag{}
+	for rows.Next() {
+		var tag models.Tag
+		if err := rows.Scan(&tag.ID, &tag.Name, &tag.CreatedAt); err != nil {
+			http.Error(w, "Erro ao ler tags", http.StatusInternalServerError)
+			return
+		}
+		tags = append(tags, tag)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tags)
+}
+
+func (h *TagHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	names := normalizeTags([]string{tag.Name})
+	if len(names) == 0 {
+		http.Error(w, "Nome da tag é obrigatório", http.StatusBadRequest)
+		return
+	}
+	tag.Name = names[0]
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(r.Context(), `INSERT INTO tags (user_id, name) VALUES ($1, $2) RETURNING id, created_at`, userID, tag.Name).Scan(&tag.ID, &tag.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			http.Error(w, "Já existe uma tag com esse nome", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao criar tag", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "tag", tag.ID, auditCreate, nil); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(tag)
+}
+
+func (h *TagHandler) UpdateTag(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	var tag models.Tag
+	if err := json.NewDecoder(r.Body).Decode(&tag); err != nil {
+		http.Error(w, "Dados inválidos", http.StatusBadRequest)
+		return
+	}
+	names := normalizeTags([]string{tag.Name})
+	if len(names) == 0 {
+		http.Error(w, "Nome da tag é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "tag", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tag", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `UPDATE tags SET name = $1 WHERE id = $2 AND user_id = $3`, names[0], id, userID)
+	if err != nil {
+		if strings.Contains(err.Error(), "duplicate key") {
+			http.Error(w, "Já existe uma tag com esse nome", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Erro ao atualizar tag", http.StatusInternalServerError)
+		return
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil || rowsAffected == 0 {
+		http.Error(w, "Tag não encontrada", http.StatusNotFound)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "tag", id, auditUpdate, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Tag atualizada com sucesso"}`))
+}
+
+func (h *TagHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	id := pathParam(r, "id")
+
+	if id == "" {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, "tag", id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar tag", http.StatusInternalServerError)
+		return
+	}
+
+	_, err = tx.ExecContext(r.Context(), `DELETE FROM tags WHERE id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao deletar tag", http.StatusInternalServerError)
+		return
+	}
+	if err := recordAudit(tx, r, userID, "tag", id, auditDelete, before); err != nil {
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,300 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/edgar-lins/controle-financeiro/internal/pdf"
+)
+
+type TaxReportHandler struct {
+	DB *sql.DB
+}
+
+const (
+	// Teto anual de dedução com educação por pessoa (IRPF 2025, ano-calendário 2024)
+	educationDeductionLimit = 3561.50
+	// Previdência privada (PGBL) é dedutível até 12% da renda tributável
+	pensionDeductionRate = 0.12
+)
+
+// taxDeductibleCategories são as categorias de gasto dedutíveis, na ordem do relatório
+var taxDeductibleCategories = []struct {
+	category string
+	label    string
+}{
+	{"saude", "Saúde"},
+	{"educacao", "Educação"},
+	{"previdencia", "Previdência privada"},
+}
+
+// GetTaxReport gera o relatório de apoio à declaração do IRPF de um ano-calendário
+// Parâmetros: year (padrão: ano anterior), format (json|csv|pdf)
+func (h *TaxReportHandler) GetTaxReport(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	year := time.Now().Year() - 1
+	if v := r.URL.Query().Get("year"); v != "" {
+		y, err := strconv.Atoi(v)
+		if err != nil || y < 1900 {
+			http.Error(w, "Ano inválido", http.StatusBadRequest)
+			return
+		}
+		year = y
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" && format != "pdf" {
+		http.Error(w, "Formato inválido, use json, csv ou pdf", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.buildTaxReport(r.Context(), userID, year)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao gerar relatório do IRPF")
+		return
+	}
+
+	filename := fmt.Sprintf("irpf_%d", year)
+	switch format {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.csv"`)
+		writeTaxReportCSV(w, report)
+	case "pdf":
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.pdf"`)
+		w.Write(taxReportPDF(report))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+func (h *TaxReportHandler) buildTaxReport(ctx context.Context, userID, year int) (models.TaxReport, error) {
+	report := models.TaxReport{
+		Year:        year,
+		Deductibles: []models.TaxDeductibleGroup{},
+		Incomes:     []models.TaxIncomeSource{},
+		Assets:      []models.TaxAssetBalance{},
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(year, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	// Rendas agrupadas por fonte (descrição), na moeda base do usuário
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT MIN(TRIM(description)), COALESCE(SUM(`+baseAmountSQL("$1")+`), 0), COUNT(*)
+		FROM incomes
+		WHERE user_id = $1 AND date BETWEEN $2 AND $3 AND deleted_at IS NULL
+		GROUP BY LOWER(TRIM(description))
+		ORDER BY 2 DESC
+	`, userID, from, to)
+	if err != nil {
+		return report, err
+	}
+	for rows.Next() {
+		var src models.TaxIncomeSource
+		if err := rows.Scan(&src.Source, &src.Total, &src.Count); err != nil {
+			rows.Close()
+			return report, err
+		}
+		report.TotalIncome += src.Total
+		report.Incomes = append(report.Incomes, src)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return report, err
+	}
+
+	// Gastos dedutíveis por categoria
+	for _, c := range taxDeductibleCategories {
+		group := models.TaxDeductibleGroup{Kind: c.category, Label: c.label, Items: []models.TaxDeductibleItem{}}
+
+		rows, err := h.DB.QueryContext(ctx, `
+			SELECT date, description, `+baseAmountSQL("$1")+`
+			FROM `+expenseLines+` expenses
+			WHERE user_id = $1 AND category = $2 AND date BETWEEN $3 AND $4
+			ORDER BY date
+		`, userID, c.category, from, to)
+		if err != nil {
+			return report, err
+		}
+		for rows.Next() {
+			var item models.TaxDeductibleItem
+			if err := rows.Scan(&item.Date, &item.Description, &item.Amount); err != nil {
+				rows.Close()
+				return report, err
+			}
+			group.Total += item.Amount
+			group.Items = append(group.Items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return report, err
+		}
+
+		group.Total = roundMoney(group.Total)
+		group.Deductible = group.Total
+		switch c.category {
+		case "educacao":
+			limit := educationDeductionLimit
+			group.Limit = &limit
+		case "previdencia":
+			limit := roundMoney(report.TotalIncome * pensionDeductionRate)
+			group.Limit = &limit
+		}
+		if group.Limit != nil && group.Deductible > *group.Limit {
+			group.Deductible = *group.Limit
+		}
+		report.TotalDeductible += group.Deductible
+		report.Deductibles = append(report.Deductibles, group)
+	}
+	report.TotalDeductible = roundMoney(report.TotalDeductible)
+	report.TotalIncome = roundMoney(report.TotalIncome)
+
+	// Bens e direitos: saldo das contas em 31/12 do ano anterior e do ano (cartões são dívidas),
+	// convertido para a moeda base na cotação de cada data
+	rows, err = h.DB.QueryContext(ctx, `
+		WITH `+accountEffectsCTE+`,
+		balances AS (
+			SELECT a.id, a.name, a.type, a.currency,
+				a.balance - COALESCE((SELECT SUM(delta) FROM daily WHERE daily.account_id = a.id AND daily.date > $2::date), 0) AS previous,
+				a.balance - COALESCE((SELECT SUM(delta) FROM daily WHERE daily.account_id = a.id AND daily.date > $3::date), 0) AS current
+			FROM accounts a
+			WHERE a.user_id = $1 AND a.type <> 'cartao' AND a.deleted_at IS NULL
+		)
+		SELECT id, name, type,
+			to_base_currency($1, previous, currency, $2::date),
+			to_base_currency($1, current, currency, $3::date)
+		FROM balances
+		ORDER BY name
+	`, userID, from.AddDate(0, 0, -1), to)
+	if err != nil {
+		return report, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var asset models.TaxAssetBalance
+		if err := rows.Scan(&asset.AccountID, &asset.Name, &asset.Type, &asset.PreviousBalance, &asset.Balance); err != nil {
+			return report, err
+		}
+		report.Assets = append(report.Assets, asset)
+	}
+
+	return report, rows.Err()
+}
+
+// formatBRL formata um valor no padrão brasileiro (1.234,56)
+func formatBRL(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	intPart, decPart := s[:len(s)-3], s[len(s)-2:]
+	var b strings.Builder
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte('.')
+		}
+		b.WriteRune(r)
+	}
+	out := b.String() + "," + decPart
+	if negative {
+		out = "-" + out
+	}
+	return out
+}
+
+func writeTaxReportCSV(w http.ResponseWriter, report models.TaxReport) {
+	cw := csv.NewWriter(w)
+	year := strconv.Itoa(report.Year)
+
+	cw.Write([]string{"RELATÓRIO AUXILIAR IRPF", "Ano-calendário " + year})
+	cw.Write([]string{})
+
+	cw.Write([]string{"RENDIMENTOS POR FONTE"})
+	cw.Write([]string{"Fonte", "Lançamentos", "Total (R$)"})
+	for _, src := range report.Incomes {
+		cw.Write([]string{src.Source, strconv.Itoa(src.Count), formatBRL(src.Total)})
+	}
+	cw.Write([]string{"Total", "", formatBRL(report.TotalIncome)})
+	cw.Write([]string{})
+
+	cw.Write([]string{"PAGAMENTOS DEDUTÍVEIS"})
+	cw.Write([]string{"Tipo", "Data", "Descrição", "Valor (R$)"})
+	for _, g := range report.Deductibles {
+		for _, item := range g.Items {
+			cw.Write([]string{g.Label, item.Date.Format("02/01/2006"), item.Description, formatBRL(item.Amount)})
+		}
+		limit := ""
+		if g.Limit != nil {
+			limit = "limite " + formatBRL(*g.Limit)
+		}
+		cw.Write([]string{g.Label, "Total", limit, formatBRL(g.Total)})
+		cw.Write([]string{g.Label, "Dedutível", "", formatBRL(g.Deductible)})
+	}
+	cw.Write([]string{"Total dedutível", "", "", formatBRL(report.TotalDeductible)})
+	cw.Write([]string{})
+
+	cw.Write([]string{"BENS E DIREITOS"})
+	cw.Write([]string{"Conta", "Tipo", "Situação em 31/12/" + strconv.Itoa(report.Year-1), "Situação em 31/12/" + year})
+	for _, a := range report.Assets {
+		cw.Write([]string{a.Name, a.Type, formatBRL(a.PreviousBalance), formatBRL(a.Balance)})
+	}
+
+	cw.Flush()
+}
+
+func taxReportPDF(report models.TaxReport) []byte {
+	doc := pdf.New()
+	year := strconv.Itoa(report.Year)
+
+	doc.Title("Relatório auxiliar IRPF - Ano-calendário " + year)
+	doc.Text("Gerado em " + time.Now().Format("02/01/2006"))
+	doc.Blank()
+
+	doc.Heading("Rendimentos por fonte")
+	doc.Row([]string{"Fonte", "Qtd", "Total (R$)"}, []int{50, -5, -16})
+	for _, src := range report.Incomes {
+		doc.Row([]string{src.Source, strconv.Itoa(src.Count), formatBRL(src.Total)}, []int{50, -5, -16})
+	}
+	doc.Row([]string{"Total", "", formatBRL(report.TotalIncome)}, []int{50, -5, -16})
+	doc.Blank()
+
+	doc.Heading("Pagamentos dedutíveis")
+	for _, g := range report.Deductibles {
+		doc.Text(g.Label)
+		for _, item := range g.Items {
+			doc.Row([]string{item.Date.Format("02/01/2006"), item.Description, formatBRL(item.Amount)}, []int{10, 44, -16})
+		}
+		total := "Total " + formatBRL(g.Total) + " | Dedutível " + formatBRL(g.Deductible)
+		if g.Limit != nil {
+			total += " (limite " + formatBRL(*g.Limit) + ")"
+		}
+		doc.Text(total)
+		doc.Blank()
+	}
+	doc.Text("Total dedutível: R$ " + formatBRL(report.TotalDeductible))
+	doc.Blank()
+
+	doc.Heading("Bens e direitos")
+	doc.Row([]string{"Conta", "Tipo", "31/12/" + strconv.Itoa(report.Year-1), "31/12/" + year}, []int{30, 12, -14, -14})
+	for _, a := range report.Assets {
+		doc.Row([]string{a.Name, a.Type, formatBRL(a.PreviousBalance), formatBRL(a.Balance)}, []int{30, 12, -14, -14})
+	}
+
+	return doc.Bytes()
+}
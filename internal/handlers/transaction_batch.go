@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+)
+
+const maxBatchOperations = 200
+
+// batchOperation é uma criação, alteração ou exclusão do lote. Data tem o mesmo
+// corpo aceito pelo endpoint individual (POST /expenses, PUT /incomes/update, ...).
+type batchOperation struct {
+	Type   string          `json:"type"`   // expense, income ou transfer
+	Action string          `json:"action"` // create, update ou delete
+	ID     int64           `json:"id"`     // obrigatório em update e delete
+	Data   json.RawMessage `json:"data"`
+}
+
+// BatchTransactions aplica uma lista de operações de gastos, rendas e transferências
+// numa única transação: ou todas são aplicadas (com os saldos das contas) ou nenhuma.
+// Todas as operações são validadas e os erros voltam juntos, com o índice de cada uma.
+func (h *TransactionHandler) BatchTransactions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	var req struct {
+		Operations []batchOperation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		http.Error(w, "Nenhuma operação informada", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > maxBatchOperations {
+		http.Error(w, fmt.Sprintf("Máximo de %d operações por lote", maxBatchOperations), http.StatusBadRequest)
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	results := make([]models.BatchResult, 0, len(req.Operations))
+	failures := []models.BatchResult{}
+	status := http.StatusUnprocessableEntity
+	for i, op := range req.Operations {
+		result := models.BatchResult{Index: i, Type: op.Type, Action: op.Action}
+
+		// Cada operação roda num savepoint: uma que falha é desfeita sozinha e as
+		// seguintes continuam sendo validadas
+		if _, err := tx.ExecContext(r.Context(), `SAVEPOINT batch_operation`); err != nil {
+			http.Error(w, "Erro ao aplicar lote", http.StatusInternalServerError)
+			return
+		}
+		result.ID, result.Data, result.Status, result.Error = h.applyBatchOperation(tx, r, userID, op)
+		if result.Error != "" {
+			if _, err := tx.ExecContext(r.Context(), `ROLLBACK TO SAVEPOINT batch_operation`); err != nil {
+				http.Error(w, "Erro ao aplicar lote", http.StatusInternalServerError)
+				return
+			}
+			if result.Status >= http.StatusInternalServerError {
+				status = http.StatusInternalServerError
+			}
+			failures = append(failures, result)
+			continue
+		}
+		if _, err := tx.ExecContext(r.Context(), `RELEASE SAVEPOINT batch_operation`); err != nil {
+			http.Error(w, "Erro ao aplicar lote", http.StatusInternalServerError)
+			return
+		}
+		results = append(results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(failures) > 0 {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":  "Nenhuma operação foi aplicada",
+			"errors": failures,
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+	for _, result := range results {
+		if result.Action == "create" {
+			metrics.TransactionsCreated.WithLabelValues(result.Type).Inc()
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+// applyBatchOperation executa uma operação do lote dentro de tx, reaproveitando as
+// regras dos endpoints individuais. Retorna o id, o registro criado e o status
+// (com a mensagem de erro quando falha).
+func (h *TransactionHandler) applyBatchOperation(tx *sql.Tx, r *http.Request, userID int, op batchOperation) (int64, interface{}, int, string) {
+	switch op.Action {
+	case "create":
+	case "update", "delete":
+		if op.Type == "transfer" {
+			return 0, nil, http.StatusBadRequest, "Transferências aceitam apenas create"
+		}
+		if op.ID <= 0 {
+			return 0, nil, http.StatusBadRequest, "ID é obrigatório"
+		}
+	default:
+		return 0, nil, http.StatusBadRequest, "Ação inválida, use create, update ou delete"
+	}
+
+	switch op.Type {
+	case "expense":
+		expenses := &ExpenseHandler{DB: h.DB}
+		var req expenseRequest
+		if op.Action != "delete" {
+			if err := json.Unmarshal(op.Data, &req); err != nil {
+				return 0, nil, http.StatusBadRequest, "Dados inválidos"
+			}
+		}
+		switch op.Action {
+		case "create":
+			expense, status, msg := expenses.createExpense(tx, r, userID, req)
+			if status != 0 {
+				return 0, nil, status, msg
+			}
+			return expense.ID, expense, http.StatusCreated, ""
+		case "update":
+			if status, msg := expenses.updateExpense(tx, r, userID, op.ID, req); status != 0 {
+				return op.ID, nil, status, msg
+			}
+		case "delete":
+			if status, msg := expenses.deleteExpense(tx, r, userID, op.ID); status != 0 {
+				return op.ID, nil, status, msg
+			}
+		}
+	case "income":
+		incomes := &IncomeHandler{DB: h.DB}
+		var req incomeRequest
+		if op.Action != "delete" {
+			if err := json.Unmarshal(op.Data, &req); err != nil {
+				return 0, nil, http.StatusBadRequest, "Dados inválidos"
+			}
+		}
+		switch op.Action {
+		case "create":
+			income, status, msg := incomes.createIncome(tx, r, userID, req)
+			if status != 0 {
+				return 0, nil, status, msg
+			}
+			return int64(income.ID), income, http.StatusCreated, ""
+		case "update":
+			if status, msg := incomes.updateIncome(tx, r, userID, op.ID, req); status != 0 {
+				return op.ID, nil, status, msg
+			}
+		case "delete":
+			if status, msg := incomes.deleteIncome(tx, r, userID, op.ID); status != 0 {
+				return op.ID, nil, status, msg
+			}
+		}
+	case "transfer":
+		var req transferRequest
+		if err := json.Unmarshal(op.Data, &req); err != nil {
+			return 0, nil, http.StatusBadRequest, "Dados inválidos"
+		}
+		accounts := &AccountHandler{DB: h.DB}
+		transferID, status, msg := accounts.createTransfer(tx, r, userID, req)
+		if status != 0 {
+			return 0, nil, status, msg
+		}
+		return transferID, nil, http.StatusCreated, ""
+	default:
+		return 0, nil, http.StatusBadRequest, "Tipo inválido, use expense, income ou transfer"
+	}
+
+	if op.Action == "delete" {
+		return op.ID, nil, http.StatusNoContent, ""
+	}
+	return op.ID, nil, http.StatusOK, ""
+}
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/lib/pq"
+)
+
+// TransactionHandler reúne operações que tratam gastos e rendas em conjunto
+type TransactionHandler struct {
+	DB *sql.DB
+}
+
+// transactionsUnion é a visão unificada de gastos e rendas do usuário $1
+const transactionsUnion = `
+	SELECT 'expense' AS kind, id, description, amount, currency, category, "group", payment_method, date, account_id
+	FROM expenses WHERE user_id = $1 AND deleted_at IS NULL
+	UNION ALL
+	SELECT 'income', id, description, amount, currency, NULL, NULL, NULL, date, account_id
+	FROM incomes WHERE user_id = $1 AND deleted_at IS NULL`
+
+// searchSortColumns mapeia o parâmetro sort para a coluna da consulta
+var searchSortColumns = map[string]string{
+	"date":        "date",
+	"amount":      "amount",
+	"description": "LOWER(description)",
+}
+
+// escapeLike escapa os curingas do LIKE para buscar o texto literalmente
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// splitParam separa um parâmetro de lista (vírgulas) ignorando vazios
+func splitParam(r *http.Request, name string) []string {
+	var values []string
+	for _, v := range strings.Split(r.URL.Query().Get(name), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// SearchTransactions busca gastos e rendas com filtros combináveis
+// Parâmetros: q, type (expense|income), min_amount, max_amount, from, to, account_id,
+// category, group, payment_method, tag (listas separadas por vírgula),
+// sort (date|amount|description), order (asc|desc), limit, offset
+func (h *TransactionHandler) SearchTransactions(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+	query := r.URL.Query()
+
+	where := ""
+	args := []interface{}{userID}
+	addArg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	// Texto livre: cada palavra deve aparecer na descrição, ignorando acentos
+	for _, term := range strings.Fields(query.Get("q")) {
+		where += " AND unaccent(LOWER(description)) LIKE unaccent(LOWER(" + addArg("%"+escapeLike(term)+"%") + "))"
+	}
+
+	if kind := query.Get("type"); kind != "" {
+		if kind != "expense" && kind != "income" {
+			http.Error(w, "Tipo inválido, use expense ou income", http.StatusBadRequest)
+			return
+		}
+		where += " AND kind = " + addArg(kind)
+	}
+
+	for param, op := range map[string]string{"min_amount": ">=", "max_amount": "<="} {
+		if v := query.Get(param); v != "" {
+			amount, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				http.Error(w, "Valor inválido em "+param, http.StatusBadRequest)
+				return
+			}
+			where += " AND amount " + op + " " + addArg(amount)
+		}
+	}
+
+	for param, op := range map[string]string{"from": ">=", "to": "<="} {
+		if v := query.Get(param); v != "" {
+			date, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				http.Error(w, "Data inválida, use YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+			where += " AND date " + op + " " + addArg(date)
+		}
+	}
+
+	if ids := splitParam(r, "account_id"); ids != nil {
+		accountIDs := make([]int64, 0, len(ids))
+		for _, v := range ids {
+			id, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				http.Error(w, "Conta inválida", http.StatusBadRequest)
+				return
+			}
+			accountIDs = append(accountIDs, id)
+		}
+		where += " AND account_id = ANY(" + addArg(pq.Array(accountIDs)) + ")"
+	}
+
+	// Filtros exclusivos de gastos: rendas nunca casam com eles. Categoria e grupo
+	// também casam com as divisões de um gasto dividido.
+	for param, column := range map[string]string{"category": "category", "group": `"group"`} {
+		if values := splitParam(r, param); values != nil {
+			arg := addArg(pq.Array(values))
+			where += " AND (" + column + " = ANY(" + arg + ") OR (kind = 'expense' AND id IN (" +
+				"SELECT expense_id FROM expense_splits WHERE " + column + " = ANY(" + arg + "))))"
+		}
+	}
+	if values := splitParam(r, "payment_method"); values != nil {
+		where += " AND payment_method = ANY(" + addArg(pq.Array(values)) + ")"
+	}
+
+	if tags := parseTagFilter(r); tags != nil {
+		pos := len(args) + 1
+		args = append(args, pq.Array(tags))
+		where += " AND ((kind = 'expense' AND id IN (" + tagSubquery("expense", pos) + "))" +
+			" OR (kind = 'income' AND id IN (" + tagSubquery("income", pos) + ")))"
+	}
+
+	sortParam := query.Get("sort")
+	if sortParam == "" {
+		sortParam = "date"
+	}
+	sortColumn, ok := searchSortColumns[sortParam]
+	if !ok {
+		http.Error(w, "Ordenação inválida, use date, amount ou description", http.StatusBadRequest)
+		return
+	}
+	order := strings.ToUpper(query.Get("order"))
+	if order == "" {
+		order = "DESC"
+	}
+	if order != "ASC" && order != "DESC" {
+		http.Error(w, "Ordem inválida, use asc ou desc", http.StatusBadRequest)
+		return
+	}
+
+	limit := defaultPageLimit
+	if v := query.Get("limit"); v != "" {
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 1 || l > maxPageLimit {
+			http.Error(w, "Parâmetro limit deve estar entre 1 e 200", http.StatusBadRequest)
+			return
+		}
+		limit = l
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		o, err := strconv.Atoi(v)
+		if err != nil || o < 0 {
+			http.Error(w, "Parâmetro offset inválido", http.StatusBadRequest)
+			return
+		}
+		offset = o
+	}
+
+	filtered := "SELECT * FROM (" + transactionsUnion + ") t WHERE TRUE" + where
+
+	// Agregados de todo o conjunto filtrado (não só da página), na moeda base
+	var count int
+	var totalExpenses, totalIncomes float64
+	var baseCurrency string
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT COUNT(*),
+			COALESCE(SUM(`+baseAmountSQL("$1")+`) FILTER (WHERE kind = 'expense'), 0),
+			COALESCE(SUM(`+baseAmountSQL("$1")+`) FILTER (WHERE kind = 'income'), 0),
+			user_base_currency($1)
+		FROM (`+filtered+`) f
+	`, args...).Scan(&count, &totalExpenses, &totalIncomes, &baseCurrency)
+	if err != nil {
+		writeConversionError(w, r, err, "Erro ao buscar transações")
+		return
+	}
+
+	pageArgs := append(append([]interface{}{}, args...), limit, offset)
+	rows, err := h.DB.QueryContext(r.Context(), filtered+
+		" ORDER BY "+sortColumn+" "+order+", id "+order+
+		" LIMIT $"+strconv.Itoa(len(args)+1)+" OFFSET $"+strconv.Itoa(len(args)+2), pageArgs...)
+	if err != nil {
+		http.Error(w, "Erro ao buscar transações", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar transações", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	items := []models.Transaction{}
+	idsByKind := map[string][]int64{}
+	for rows.Next() {
+		var t models.Transaction
+		if err := rows.Scan(&t.Kind, &t.ID, &t.Description, &t.Amount, &t.Currency, &t.Category, &t.Group, &t.PaymentMethod, &t.Date, &t.AccountID); err != nil {
+			http.Error(w, "Erro ao ler transações", http.StatusInternalServerError)
+			return
+		}
+		items = append(items, t)
+		idsByKind[t.Kind] = append(idsByKind[t.Kind], t.ID)
+	}
+
+	for kind, ids := range idsByKind {
+		tags, err := loadTransactionTags(r.Context(), h.DB, userID, kind, ids)
+		if err != nil {
+			http.Error(w, "Erro ao buscar tags", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao buscar tags", "error", err)
+			return
+		}
+		for i := range items {
+			if items[i].Kind == kind {
+				items[i].Tags = tags[items[i].ID]
+			}
+		}
+	}
+	for i := range items {
+		if items[i].Tags == nil {
+			items[i].Tags = []string{}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":          items,
+		"count":          count,
+		"total_expenses": totalExpenses,
+		"total_incomes":  totalIncomes,
+		"currency":       baseCurrency,
+		"limit":          limit,
+		"offset":         offset,
+	})
+}
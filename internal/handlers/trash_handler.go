@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/models"
+	"github.com/edgar-lins/controle-financeiro/internal/storage"
+)
+
+const (
+	defaultTrashRetentionDays = 30
+	trashPurgeInterval        = 24 * time.Hour
+)
+
+// trashTypes são os tipos que vão para a lixeira, na ordem da limpeza
+// (contas por último, depois dos lançamentos e metas vinculados a elas)
+var trashTypes = []string{"expense", "income", "goal", "account"}
+
+// trashItemSQL seleciona os itens de cada tipo no formato de models.TrashItem
+var trashItemSQL = map[string]string{
+	"expense": `SELECT 'expense', id, COALESCE(description, ''), amount, date, currency, deleted_at FROM expenses`,
+	"income":  `SELECT 'income', id, COALESCE(description, ''), amount, date, currency, deleted_at FROM incomes`,
+	"goal":    `SELECT 'goal', id, name, target_amount, deadline, user_base_currency(user_id), deleted_at FROM goals`,
+	"account": `SELECT 'account', id, name, balance, NULL::date, currency, deleted_at FROM accounts`,
+}
+
+type TrashHandler struct {
+	DB        *sql.DB
+	Storage   storage.Storage
+	Retention time.Duration // tempo na lixeira antes da exclusão definitiva
+}
+
+func (h *TrashHandler) retention() time.Duration {
+	if h.Retention > 0 {
+		return h.Retention
+	}
+	return defaultTrashRetentionDays * 24 * time.Hour
+}
+
+// GetTrash lista os itens na lixeira, dos excluídos mais recentemente para os mais antigos
+// Parâmetro opcional: type (expense, income, goal ou account)
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	types := trashTypes
+	if v := r.URL.Query().Get("type"); v != "" {
+		if _, ok := trashItemSQL[v]; !ok {
+			http.Error(w, "Tipo inválido, use expense, income, goal ou account", http.StatusBadRequest)
+			return
+		}
+		types = []string{v}
+	}
+
+	selects := make([]string, 0, len(types))
+	for _, t := range types {
+		selects = append(selects, trashItemSQL[t]+` WHERE user_id = $1 AND deleted_at IS NOT NULL`)
+	}
+
+	rows, err := h.DB.QueryContext(r.Context(), strings.Join(selects, " UNION ALL ")+` ORDER BY 7 DESC, 2 DESC`, userID)
+	if err != nil {
+		http.Error(w, "Erro ao buscar lixeira", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao buscar lixeira", "error", err)
+		return
+	}
+	defer rows.Close()
+
+	items := []models.TrashItem{}
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.Description, &item.Amount, &item.Date, &item.Currency, &item.DeletedAt); err != nil {
+			http.Error(w, "Erro ao ler lixeira", http.StatusInternalServerError)
+			return
+		}
+		item.Currency = strings.TrimSpace(item.Currency)
+		item.PurgeAt = item.DeletedAt.Add(h.retention())
+		items = append(items, item)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
+
+// RestoreTrash tira um item da lixeira (POST /v1/trash/{type}/{id}/restore). Gastos e rendas voltam a
+// afetar o saldo da conta, desfazendo o estorno feito na exclusão.
+func (h *TrashHandler) RestoreTrash(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userIDVal := r.Context().Value(middleware.UserIDKey)
+	userID, _ := userIDVal.(int)
+
+	entityType := pathParam(r, "type")
+	if _, ok := trashItemSQL[entityType]; !ok {
+		http.Error(w, "Tipo inválido, use expense, income, goal ou account", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseInt(pathParam(r, "id"), 10, 64)
+	if err != nil {
+		http.Error(w, "ID é obrigatório", http.StatusBadRequest)
+		return
+	}
+	table := auditTables[entityType]
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, "Erro ao iniciar transação", http.StatusInternalServerError)
+		return
+	}
+	defer tx.Rollback()
+
+	before, err := auditSnapshot(r.Context(), tx, userID, entityType, id)
+	if err != nil {
+		http.Error(w, "Erro ao buscar item", http.StatusInternalServerError)
+		return
+	}
+
+	result, err := tx.ExecContext(r.Context(), `UPDATE `+table+` SET deleted_at = NULL WHERE id = $1 AND user_id = $2 AND deleted_at IS NOT NULL`, id, userID)
+	if err != nil {
+		http.Error(w, "Erro ao restaurar item", http.StatusInternalServerError)
+		return
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		http.Error(w, "Item não encontrado na lixeira", http.StatusNotFound)
+		return
+	}
+
+	if entityType == "expense" || entityType == "income" {
+		var amount float64
+		var accountID *int64
+		var date time.Time
+		err = tx.QueryRowContext(r.Context(), `SELECT COALESCE(account_amount, amount), account_id, date FROM `+table+` WHERE id = $1`, id).Scan(&amount, &accountID, &date)
+		if err != nil {
+			http.Error(w, "Erro ao restaurar item", http.StatusInternalServerError)
+			return
+		}
+
+		// Reaplica o efeito no saldo: gasto debita, renda credita
+		if entityType == "expense" {
+			amount = -amount
+		}
+		if accountID != nil {
+			_, err = tx.ExecContext(r.Context(), `UPDATE accounts SET balance = balance + $1 WHERE id = $2 AND user_id = $3`, amount, accountID, userID)
+			if err != nil {
+				http.Error(w, "Erro ao atualizar saldo da conta", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// O lançamento volta a contar no patrimônio (e, se for parcela, no saldo
+		// devedor do empréstimo) a partir da sua data
+		if err := staleNetWorthFrom(r.Context(), tx, userID, date); err != nil {
+			http.Error(w, "Erro ao invalidar patrimônio", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+			return
+		}
+	}
+
+	// Parcela de empréstimo: o pagamento volta a contar no saldo devedor
+	if entityType == "expense" {
+		loanID, err := linkedLoan(r.Context(), tx, id)
+		if err != nil {
+			http.Error(w, "Erro ao buscar empréstimo do gasto", http.StatusInternalServerError)
+			return
+		}
+		if loanID != nil {
+			if err := recalcLoan(r.Context(), tx, *loanID); err != nil {
+				http.Error(w, "Erro ao recalcular empréstimo", http.StatusInternalServerError)
+				slog.ErrorContext(r.Context(), "Erro ao recalcular empréstimo", "error", err)
+				return
+			}
+		}
+	}
+
+	if err := recordAudit(tx, r, userID, entityType, id, auditUpdate, before); err != nil {
+		http.Error(w, "Erro ao registrar auditoria", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao registrar auditoria", "error", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, "Erro ao confirmar transação", http.StatusInternalServerError)
+		return
+	}
+
+	// A conta volta a compor o patrimônio
+	if entityType == "account" {
+		if err := invalidateNetWorth(r.Context(), h.DB, userID); err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao invalidar patrimônio", "error", err)
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"message": "Item restaurado com sucesso"}`))
+}
+
+// StartTrashPurge apaga de vez, na inicialização e depois uma vez por dia, os itens
+// que estão na lixeira há mais tempo que retention. Para quando ctx é cancelado.
+func StartTrashPurge(ctx context.Context, db *sql.DB, store storage.Storage, retention time.Duration) {
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			purged, err := purgeTrash(ctx, db, store, time.Now().Add(-retention))
+			if err != nil {
+				slog.Error("Erro ao limpar lixeira", "error", err)
+			} else if purged > 0 {
+				slog.Info("Lixeira: itens apagados definitivamente", "count", purged)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// purgeTrash apaga os itens excluídos antes de cutoff, com seus anexos. Um item que
+// não pode ser apagado (ex.: conta com posições em investimentos) fica para a próxima vez.
+func purgeTrash(ctx context.Context, db *sql.DB, store storage.Storage, cutoff time.Time) (int, error) {
+	purged := 0
+	for _, entityType := range trashTypes {
+		table := auditTables[entityType]
+
+		rows, err := db.QueryContext(ctx, `SELECT id, user_id FROM `+table+` WHERE deleted_at < $1`, cutoff)
+		if err != nil {
+			return purged, err
+		}
+		type trashed struct {
+			id     int64
+			userID int
+		}
+		var items []trashed
+		for rows.Next() {
+			var t trashed
+			if err := rows.Scan(&t.id, &t.userID); err != nil {
+				rows.Close()
+				return purged, err
+			}
+			items = append(items, t)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return purged, err
+		}
+
+		for _, t := range items {
+			keys, err := purgeTrashItem(ctx, db, entityType, table, t.id, t.userID)
+			if err != nil {
+				slog.Error("Erro ao apagar item da lixeira", "type", entityType, "id", t.id, "error", err)
+				continue
+			}
+			removeStoredFiles(store, keys)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// purgeTrashItem apaga um item da lixeira e os registros dos anexos, retornando as
+// chaves dos arquivos a remover do armazenamento depois do commit
+func purgeTrashItem(ctx context.Context, db *sql.DB, entityType, table string, id int64, userID int) ([]string, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var keys []string
+	if _, ok := attachmentEntities[entityType]; ok {
+		if keys, err = deleteEntityAttachments(ctx, tx, userID, entityType, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM `+table+` WHERE id = $1 AND deleted_at IS NOT NULL`, id); err != nil {
+		return nil, err
+	}
+
+	return keys, tx.Commit()
+}
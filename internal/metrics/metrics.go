@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Métricas expostas em /metrics, no registro padrão do Prometheus (que já inclui as do
+// runtime Go e do processo)
+var (
+	// HTTPRequests e HTTPDuration usam como rota o padrão do mux (ex.: "GET /v1/expenses/{id}"),
+	// e não o caminho, para não criar uma série por id
+	HTTPRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_requests_total",
+		Help: "Requisições HTTP por rota, método e status.",
+	}, []string{"route", "method", "status"})
+
+	HTTPDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Duração das requisições HTTP por rota, método e status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// LoginAttempts conta os logins por resultado: success, invalid_credentials ou rate_limited
+	LoginAttempts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "login_attempts_total",
+		Help: "Tentativas de login por resultado.",
+	}, []string{"result"})
+
+	Signups = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "signups_total",
+		Help: "Contas de usuário criadas.",
+	})
+
+	// TransactionsCreated conta gastos, rendas e transferências criados (individualmente ou em lote)
+	TransactionsCreated = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "transactions_created_total",
+		Help: "Transações criadas por tipo (expense, income, transfer).",
+	}, []string{"type"})
+)
+
+// RegisterDB expõe as estatísticas do pool de conexões (db.Stats()) como go_sql_*
+func RegisterDB(db *sql.DB, name string) {
+	prometheus.MustRegister(collectors.NewDBStatsCollector(db, name))
+}
+
+// Handler serve as métricas no formato do Prometheus. Com token, exige o cabeçalho
+// Authorization: Bearer <token> (o bearer_token do scrape do Prometheus); sem token
+// (só fora de produção) fica aberto.
+func Handler(token string) http.Handler {
+	handler := promhttp.Handler()
+	if token == "" {
+		return handler
+	}
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), expected) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+			http.Error(w, "Token de métricas inválido", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -3,7 +3,6 @@ package middleware
 import (
 	"context"
 	"net/http"
-	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -12,36 +11,47 @@ type ctxKey string
 
 const UserIDKey ctxKey = "userID"
 
-func WithAuth(next http.HandlerFunc) http.HandlerFunc {
+// Auth valida os tokens emitidos no login, assinados com Secret (config.JWTSecret)
+type Auth struct {
+	Secret string
+}
+
+func (a Auth) WithAuth(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		auth := r.Header.Get("Authorization")
-		if auth == "" || len(auth) < 8 || auth[:7] != "Bearer " {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		tokenStr := auth[7:]
-		secret := os.Getenv("JWT_SECRET")
-		if secret == "" {
-			secret = "dev-secret-change-me"
-		}
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
+		userID, ok := a.tokenUserID(r)
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		userID, ok := claims["sub"].(float64)
-		if !ok {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
+		// Usuário no log de acesso da requisição
+		if entry, ok := r.Context().Value(accessLogKey).(*accessLog); ok {
+			entry.userID = userID
 		}
-		ctx := context.WithValue(r.Context(), UserIDKey, int(userID))
+		ctx := context.WithValue(r.Context(), UserIDKey, userID)
 		next(w, r.WithContext(ctx))
 	}
 }
+
+// tokenUserID valida o Bearer token da requisição e retorna o usuário (sub)
+func (a Auth) tokenUserID(r *http.Request) (int, bool) {
+	auth := r.Header.Get("Authorization")
+	if auth == "" || len(auth) < 8 || auth[:7] != "Bearer " {
+		return 0, false
+	}
+	tokenStr := auth[7:]
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		return []byte(a.Secret), nil
+	})
+	if err != nil || !token.Valid {
+		return 0, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return 0, false
+	}
+	userID, ok := claims["sub"].(float64)
+	if !ok {
+		return 0, false
+	}
+	return int(userID), true
+}
@@ -0,0 +1,220 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotencyBodySize limita o corpo guardado em memória para calcular o hash:
+	// cobre o maior POST aceito pela API (anexo de 10 MB mais o multipart)
+	maxIdempotencyBodySize = 11 << 20
+
+	// idempotencyPendingTimeout libera uma chave cuja primeira requisição não terminou
+	// (ex.: o servidor caiu no meio), para que o cliente possa repetir. Fica bem acima
+	// do WriteTimeout do servidor (60s, em cmd/api/main.go) para nunca liberar a chave
+	// de uma requisição que ainda está em andamento.
+	idempotencyPendingTimeout = 5 * time.Minute
+)
+
+// idempotencyRecorder repassa a resposta ao cliente e guarda uma cópia para reenvio
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
+
+// WithIdempotency trata o cabeçalho Idempotency-Key nos POSTs autenticados: a primeira
+// requisição com a chave é executada e sua resposta guardada por retention; repetições
+// com o mesmo corpo recebem a resposta original (com Idempotent-Replayed: true) sem
+// executar de novo. A chave é por usuário; reusá-la com outro corpo retorna 422.
+func WithIdempotency(db *sql.DB, auth Auth, retention time.Duration, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+		if r.Method != http.MethodPost || key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Sem token válido a rota responde 401 (ou é pública) e não há usuário para a chave
+		userID, ok := auth.tokenUserID(r)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			http.Error(w, fmt.Sprintf("Idempotency-Key deve ter no máximo %d caracteres", maxIdempotencyKeyLength), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotencyBodySize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "Corpo da requisição muito grande", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "Erro ao ler corpo da requisição", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, body)
+
+		// Descarta as chaves vencidas do usuário e as pendentes abandonadas desta chave
+		_, err = db.ExecContext(r.Context(), `
+			DELETE FROM idempotency_keys
+			WHERE user_id = $1 AND (created_at < $2 OR (key = $3 AND status IS NULL AND created_at < $4))
+		`, userID, time.Now().Add(-retention), key, time.Now().Add(-idempotencyPendingTimeout))
+		if err != nil {
+			http.Error(w, "Erro ao verificar Idempotency-Key", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao verificar Idempotency-Key", "error", err)
+			return
+		}
+
+		var id int64
+		err = db.QueryRowContext(r.Context(), `
+			INSERT INTO idempotency_keys (user_id, key, request_hash) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, key) DO NOTHING
+			RETURNING id
+		`, userID, key, hash).Scan(&id)
+		if err == sql.ErrNoRows {
+			replayIdempotent(db, w, r, userID, key, hash)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Erro ao verificar Idempotency-Key", http.StatusInternalServerError)
+			slog.ErrorContext(r.Context(), "Erro ao verificar Idempotency-Key", "error", err)
+			return
+		}
+
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r)
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		// A resposta já foi produzida (e o que ela alterou, gravado): guarda a chave mesmo
+		// que o cliente tenha desconectado
+		ctx := context.WithoutCancel(r.Context())
+
+		// Erros internos não ficam guardados: a requisição foi desfeita e pode ser repetida
+		if rec.status >= http.StatusInternalServerError {
+			if _, err := db.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE id = $1`, id); err != nil {
+				slog.ErrorContext(r.Context(), "Erro ao liberar Idempotency-Key", "error", err)
+			}
+			return
+		}
+		_, err = db.ExecContext(ctx, `
+			UPDATE idempotency_keys SET status = $1, content_type = $2, response = $3 WHERE id = $4
+		`, rec.status, rec.Header().Get("Content-Type"), rec.body.Bytes(), id)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Erro ao guardar resposta da Idempotency-Key", "error", err)
+		}
+	})
+}
+
+// replayIdempotent devolve a resposta guardada para a chave já usada
+func replayIdempotent(db *sql.DB, w http.ResponseWriter, r *http.Request, userID int, key, hash string) {
+	var storedHash string
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var response []byte
+	err := db.QueryRowContext(r.Context(), `
+		SELECT request_hash, status, content_type, response FROM idempotency_keys WHERE user_id = $1 AND key = $2
+	`, userID, key).Scan(&storedHash, &status, &contentType, &response)
+	if err == sql.ErrNoRows {
+		// Liberada entre o INSERT e a consulta (a primeira falhou): o cliente pode repetir
+		http.Error(w, "Requisição com esta Idempotency-Key falhou, tente novamente", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Erro ao verificar Idempotency-Key", http.StatusInternalServerError)
+		slog.ErrorContext(r.Context(), "Erro ao verificar Idempotency-Key", "error", err)
+		return
+	}
+
+	if storedHash != hash {
+		http.Error(w, "Idempotency-Key já usada em outra requisição", http.StatusUnprocessableEntity)
+		return
+	}
+	if !status.Valid {
+		http.Error(w, "Requisição com esta Idempotency-Key ainda em processamento", http.StatusConflict)
+		return
+	}
+
+	if contentType.String != "" {
+		w.Header().Set("Content-Type", contentType.String)
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+	w.WriteHeader(int(status.Int64))
+	w.Write(response)
+}
+
+// requestHash identifica a requisição pelo método, caminho, query e corpo. Corpo
+// multipart (upload de anexo) entra pelas partes, porque o boundary muda a cada
+// envio do cliente
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	if !writeMultipartHash(h, r.Header.Get("Content-Type"), body) {
+		h.Write(body)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// writeMultipartHash escreve em h o nome do campo, o nome do arquivo e o sha256 do
+// conteúdo de cada parte. Retorna false (sem escrever nada) quando o corpo não é um
+// multipart válido, e aí vale o corpo bruto.
+func writeMultipartHash(h io.Writer, contentType string, body []byte) bool {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || !strings.HasPrefix(mediaType, "multipart/") || params["boundary"] == "" {
+		return false
+	}
+
+	var parts bytes.Buffer
+	reader := multipart.NewReader(bytes.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false
+		}
+		content := sha256.New()
+		if _, err := io.Copy(content, part); err != nil {
+			return false
+		}
+		fmt.Fprintf(&parts, "%q %q %x\n", part.FormName(), part.FileName(), content.Sum(nil))
+	}
+	h.Write(parts.Bytes())
+	return true
+}
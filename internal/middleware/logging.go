@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+const accessLogKey ctxKey = "accessLog"
+
+// accessLog guarda o que só se sabe dentro da cadeia (o usuário, definido pelo WithAuth)
+// para o log de acesso escrito no fim da requisição
+type accessLog struct {
+	userID int
+}
+
+// statusRecorder registra o status e o tamanho da resposta
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (rec *statusRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *statusRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	n, err := rec.ResponseWriter.Write(b)
+	rec.bytes += n
+	return n, err
+}
+
+// WithAccessLog escreve uma linha de log por requisição com método, caminho, status,
+// duração e usuário. Respostas 5xx saem com nível error.
+func WithAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := context.WithValue(r.Context(), accessLogKey, &accessLog{})
+		rec := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(rec, r.WithContext(ctx))
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		slog.Log(ctx, level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"duration_ms", float64(time.Since(start).Microseconds())/1000,
+			"bytes", rec.bytes,
+			"ip", ClientIP(r),
+		)
+	})
+}
+
+// LogHandler acrescenta a cada registro de log o request_id e o user_id da requisição
+// (quando o log é escrito com o contexto dela, ex.: slog.ErrorContext(r.Context(), ...))
+type LogHandler struct {
+	slog.Handler
+}
+
+func NewLogHandler(h slog.Handler) *LogHandler {
+	return &LogHandler{Handler: h}
+}
+
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if id, ok := ctx.Value(RequestIDKey).(string); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	userID, ok := ctx.Value(UserIDKey).(int)
+	if !ok {
+		if entry, found := ctx.Value(accessLogKey).(*accessLog); found {
+			userID, ok = entry.userID, entry.userID != 0
+		}
+	}
+	if ok {
+		record.AddAttrs(slog.Int("user_id", userID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
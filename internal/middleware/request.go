@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"strings"
+)
+
+const (
+	RequestIDKey ctxKey = "requestID"
+	clientIPKey  ctxKey = "clientIP"
+)
+
+const maxRequestIDLength = 64
+
+// WithRequestID reaproveita o X-Request-ID recebido (ou gera um novo), guarda no
+// contexto e devolve no cabeçalho da resposta
+func WithRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSpace(r.Header.Get("X-Request-ID"))
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID retorna o id da requisição atual ("" fora do WithRequestID)
+func RequestID(r *http.Request) string {
+	id, _ := r.Context().Value(RequestIDKey).(string)
+	return id
+}
+
+// WithClientIP resolve o IP de origem uma vez por requisição. X-Forwarded-For só é
+// considerado atrás de proxies confiáveis (trustedHops, config.TrustedProxyHops): cada
+// proxy acrescenta à direita o endereço de quem o chamou, então o cliente é o valor
+// trustedHops posições a partir da direita; os anteriores podem ter sido enviados pelo
+// próprio cliente. Sem proxies confiáveis vale o RemoteAddr.
+func WithClientIP(trustedHops int, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := remoteIP(r)
+		if trustedHops > 0 {
+			if forwarded := forwardedFor(r); len(forwarded) >= trustedHops {
+				ip = forwarded[len(forwarded)-trustedHops]
+			}
+		}
+		ctx := context.WithValue(r.Context(), clientIPKey, ip)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ClientIP retorna o IP de origem resolvido pelo WithClientIP (o RemoteAddr fora dele)
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// forwardedFor junta os valores de todos os cabeçalhos X-Forwarded-For, na ordem
+func forwardedFor(r *http.Request) []string {
+	var ips []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		for _, ip := range strings.Split(header, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				ips = append(ips, ip)
+			}
+		}
+	}
+	return ips
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type Attachment struct {
+	ID          int64     `json:"id"`
+	EntityType  string    `json:"entity_type"`
+	EntityID    int64     `json:"entity_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentUsage é o espaço ocupado pelos anexos do usuário
+type AttachmentUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
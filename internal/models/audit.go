@@ -0,0 +1,19 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEntry registra uma criação, alteração ou exclusão feita pelo usuário
+type AuditEntry struct {
+	ID         int64           `json:"id"`
+	EntityType string          `json:"entity_type"`
+	EntityID   int64           `json:"entity_id"`
+	Action     string          `json:"action"`           // create, update ou delete
+	Before     json.RawMessage `json:"before,omitempty"` // ausente em criações
+	After      json.RawMessage `json:"after,omitempty"`  // ausente em exclusões
+	RequestID  *string         `json:"request_id,omitempty"`
+	IP         *string         `json:"ip,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
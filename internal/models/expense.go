@@ -3,12 +3,25 @@ package models
 import "time"
 
 type Expense struct {
-	ID            int64     `json:"id"`
-	Description   string    `json:"description"`
-	Amount        float64   `json:"amount"`
-	Category      string    `json:"category"`
-	Group         string    `json:"group"`
-	PaymentMethod string    `json:"payment_method"`
-	Date          time.Time `json:"date"`
-	AccountID     *int64    `json:"account_id"`
+	ID            int64          `json:"id"`
+	Description   string         `json:"description"`
+	Amount        float64        `json:"amount"`
+	Currency      string         `json:"currency"`
+	AccountAmount *float64       `json:"account_amount,omitempty"` // valor na moeda da conta, quando difere
+	Category      string         `json:"category"`
+	Group         string         `json:"group"`
+	PaymentMethod string         `json:"payment_method"`
+	Date          time.Time      `json:"date"`
+	AccountID     *int64         `json:"account_id"`
+	Tags          []string       `json:"tags"`
+	Splits        []ExpenseSplit `json:"splits"`
+}
+
+// ExpenseSplit é uma linha de um gasto dividido entre categorias
+type ExpenseSplit struct {
+	ID          int64   `json:"id"`
+	Description string  `json:"description,omitempty"`
+	Category    string  `json:"category"`
+	Group       string  `json:"group"`
+	Amount      float64 `json:"amount"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type ForecastEvent struct {
+	Date        time.Time `json:"date"`
+	AccountID   int64     `json:"account_id"`
+	Amount      float64   `json:"amount"` // positivo entra na conta, negativo sai
+	Kind        string    `json:"kind"`   // income, expense, transfer, recurring_income, recurring_expense, card_payment, goal_contribution, loan_payment
+	Description string    `json:"description"`
+}
+
+type ForecastDay struct {
+	Date     time.Time         `json:"date"`
+	Total    float64           `json:"total"`
+	Balances map[int64]float64 `json:"balances"`
+	Events   []ForecastEvent   `json:"events,omitempty"`
+}
+
+type AccountForecast struct {
+	AccountID         int64      `json:"account_id"`
+	Name              string     `json:"name"`
+	Type              string     `json:"type"`
+	StartBalance      float64    `json:"start_balance"`
+	EndBalance        float64    `json:"end_balance"`
+	MinBalance        float64    `json:"min_balance"`
+	MinBalanceDate    time.Time  `json:"min_balance_date"`
+	FirstNegativeDate *time.Time `json:"first_negative_date,omitempty"` // não se aplica a cartões
+}
+
+type RecurringPattern struct {
+	Kind        string  `json:"kind"` // income, expense
+	Description string  `json:"description"`
+	AccountID   int64   `json:"account_id"`
+	Amount      float64 `json:"amount"`
+	Day         int     `json:"day"`
+	Occurrences int     `json:"occurrences"`
+}
+
+type CashFlowForecast struct {
+	From      time.Time          `json:"from"`
+	To        time.Time          `json:"to"`
+	Accounts  []AccountForecast  `json:"accounts"`
+	Recurring []RecurringPattern `json:"recurring"`
+	Days      []ForecastDay      `json:"days"`
+}
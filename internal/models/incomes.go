@@ -3,11 +3,14 @@ package models
 import "time"
 
 type Income struct {
-	ID          int       `json:"id"`
-	Description string    `json:"description"`
-	Amount      float64   `json:"amount"`
-	Date        time.Time `json:"date"`
-	Month       int       `json:"month"`
-	Year        int       `json:"year"`
-	AccountID   *int64    `json:"account_id"`
+	ID            int       `json:"id"`
+	Description   string    `json:"description"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	AccountAmount *float64  `json:"account_amount,omitempty"` // valor na moeda da conta, quando difere
+	Date          time.Time `json:"date"`
+	Month         int       `json:"month"`
+	Year          int       `json:"year"`
+	AccountID     *int64    `json:"account_id"`
+	Tags          []string  `json:"tags"`
 }
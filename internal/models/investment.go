@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Holding é um ativo dentro de uma conta de investimento
+type Holding struct {
+	ID           int64   `json:"id"`
+	AccountID    int64   `json:"account_id"`
+	Symbol       string  `json:"symbol"` // ticker ou nome do título de renda fixa
+	Name         string  `json:"name"`
+	AssetClass   string  `json:"asset_class"` // acao, fii, etf, bdr, renda_fixa, tesouro, fundo, cripto, outro
+	Quantity     float64 `json:"quantity"`
+	AveragePrice float64 `json:"average_price"` // preço médio, com as taxas de compra
+
+	// Posição anterior às operações registradas
+	InitialQuantity     float64   `json:"initial_quantity"`
+	InitialAveragePrice float64   `json:"initial_average_price"`
+	StartDate           time.Time `json:"start_date"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type InvestmentOperation struct {
+	ID           int64     `json:"id"`
+	HoldingID    int64     `json:"holding_id"`
+	Symbol       string    `json:"symbol,omitempty"`
+	Type         string    `json:"type"` // buy, sell, dividend
+	Quantity     float64   `json:"quantity"`
+	Price        float64   `json:"price"`
+	Fees         float64   `json:"fees"`
+	Amount       float64   `json:"amount"`                  // efeito no caixa da conta (negativo nas compras)
+	RealizedGain *float64  `json:"realized_gain,omitempty"` // lucro/prejuízo das vendas sobre o preço médio
+	Description  string    `json:"description"`
+	Date         time.Time `json:"date"`
+}
+
+type AssetQuote struct {
+	ID     int64     `json:"id"`
+	Symbol string    `json:"symbol"`
+	Date   time.Time `json:"date"`
+	Price  float64   `json:"price"`
+}
+
+// Position é a posição de um ativo avaliada pela última cotação, na moeda da conta
+type Position struct {
+	HoldingID             int64      `json:"holding_id"`
+	AccountID             int64      `json:"account_id"`
+	Symbol                string     `json:"symbol"`
+	Name                  string     `json:"name"`
+	AssetClass            string     `json:"asset_class"`
+	Currency              string     `json:"currency"`
+	Quantity              float64    `json:"quantity"`
+	AveragePrice          float64    `json:"average_price"`
+	Cost                  float64    `json:"cost"`
+	LastPrice             *float64   `json:"last_price"` // nil sem cotação: avaliada pelo preço médio
+	PriceDate             *time.Time `json:"price_date"`
+	MarketValue           float64    `json:"market_value"`
+	UnrealizedGain        float64    `json:"unrealized_gain"`
+	UnrealizedGainPercent float64    `json:"unrealized_gain_percent"`
+	AllocationPercent     float64    `json:"allocation_percent"`
+}
+
+type AssetAllocation struct {
+	AssetClass  string  `json:"asset_class"`
+	MarketValue float64 `json:"market_value"`
+	Percent     float64 `json:"percent"`
+}
+
+// Portfolio consolida as posições; os totais ficam na moeda base do usuário
+type Portfolio struct {
+	Currency       string            `json:"currency"`
+	Cash           float64           `json:"cash"` // saldo das contas de investimento
+	Cost           float64           `json:"cost"`
+	MarketValue    float64           `json:"market_value"`
+	UnrealizedGain float64           `json:"unrealized_gain"`
+	RealizedGain   float64           `json:"realized_gain"`
+	Dividends      float64           `json:"dividends"`
+	Total          float64           `json:"total"` // caixa + valor de mercado
+	Positions      []Position        `json:"positions"`
+	Allocation     []AssetAllocation `json:"allocation"`
+}
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+type Loan struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Lender     string    `json:"lender"`
+	Principal  float64   `json:"principal"`
+	AnnualRate float64   `json:"annual_rate"` // juros ao ano (%)
+	TermMonths int       `json:"term_months"`
+	System     string    `json:"system"`     // sac ou price
+	StartDate  time.Time `json:"start_date"` // contratação; a parcela n vence n meses depois
+	AccountID  *int64    `json:"account_id,omitempty"`
+	Currency   string    `json:"currency"` // principal, parcelas e saldo devedor
+	CreatedAt  time.Time `json:"created_at"`
+
+	// Calculados a partir dos pagamentos e da tabela de amortização
+	OutstandingBalance    float64          `json:"outstanding_balance"`
+	PrincipalPaid         float64          `json:"principal_paid"`
+	InterestPaid          float64          `json:"interest_paid"`
+	RemainingInterest     float64          `json:"remaining_interest"`
+	PaidInstallments      int              `json:"paid_installments"`
+	RemainingInstallments int              `json:"remaining_installments"`
+	NextInstallment       *LoanInstallment `json:"next_installment,omitempty"`
+	PayoffDate            *time.Time       `json:"payoff_date,omitempty"`
+}
+
+// LoanInstallment é uma linha da tabela de amortização: paga (valores reais do
+// pagamento) ou projetada a partir do saldo devedor atual
+type LoanInstallment struct {
+	Number    int        `json:"number"`
+	DueDate   time.Time  `json:"due_date"`
+	Amount    float64    `json:"amount"`
+	Principal float64    `json:"principal"`
+	Interest  float64    `json:"interest"`
+	Balance   float64    `json:"balance"` // saldo devedor após a parcela
+	Paid      bool       `json:"paid"`
+	PaymentID *int64     `json:"payment_id,omitempty"`
+	ExpenseID *int64     `json:"expense_id,omitempty"`
+	PaidDate  *time.Time `json:"paid_date,omitempty"`
+}
+
+type LoanPayment struct {
+	ID           int64     `json:"id"`
+	LoanID       int64     `json:"loan_id"`
+	ExpenseID    int64     `json:"expense_id"`
+	Number       int       `json:"number"`
+	Date         time.Time `json:"date"`
+	Amount       float64   `json:"amount"`
+	Principal    float64   `json:"principal"`
+	Interest     float64   `json:"interest"`
+	BalanceAfter float64   `json:"balance_after"`
+}
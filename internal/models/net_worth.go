@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+type AccountBalanceSnapshot struct {
+	AccountID int64     `json:"account_id"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Currency  string    `json:"currency"`
+	Date      time.Time `json:"date"`
+	Balance   float64   `json:"balance"`
+}
+
+type NetWorthSnapshot struct {
+	Date        time.Time                `json:"date"`
+	Assets      float64                  `json:"assets"`      // soma das contas que não são cartão e das posições em investimentos
+	Liabilities float64                  `json:"liabilities"` // dívida dos cartões e saldo devedor dos empréstimos (valor positivo)
+	NetWorth    float64                  `json:"net_worth"`
+	Accounts    []AccountBalanceSnapshot `json:"accounts,omitempty"`
+}
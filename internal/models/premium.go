@@ -8,8 +8,25 @@ type Account struct {
 	Name      string    `json:"name"`
 	Type      string    `json:"type"` // corrente, poupanca, cartao, investimento
 	Balance   float64   `json:"balance"`
+	Currency  string    `json:"currency"`        // ISO 4217 (BRL, USD, EUR...)
 	Opening   float64   `json:"opening_balance"` // saldo inicial informado pelo usuário
 	CreatedAt time.Time `json:"created_at"`
+
+	// Fatura (apenas cartao): usados na previsão de fluxo de caixa
+	StatementClosingDay *int   `json:"statement_closing_day,omitempty"`
+	StatementDueDay     *int   `json:"statement_due_day,omitempty"`
+	PaymentAccountID    *int64 `json:"payment_account_id,omitempty"`
+}
+
+type Transfer struct {
+	ID            int64     `json:"id"`
+	FromAccountID *int64    `json:"from_account_id"`
+	ToAccountID   *int64    `json:"to_account_id"`
+	Amount        float64   `json:"amount"`
+	ToAmount      *float64  `json:"to_amount,omitempty"` // creditado no destino quando as moedas diferem
+	Description   string    `json:"description"`
+	Date          time.Time `json:"date"`
+	Tags          []string  `json:"tags"`
 }
 
 type Goal struct {
@@ -21,5 +38,40 @@ type Goal struct {
 	Deadline      *time.Time `json:"deadline,omitempty"`
 	CreatedAt     time.Time  `json:"created_at"`
 	CompletedAt   *time.Time `json:"completed_at,omitempty"`
-	Progress      float64    `json:"progress"` // calculated field
+	YieldRate     *float64   `json:"yield_rate,omitempty"` // rendimento anual (%) para metas aplicadas
+	Progress      float64    `json:"progress"`             // calculated field
+
+	// Meta lastreada em conta: CurrentAmount é derivado do saldo da conta
+	AccountID       *int64   `json:"account_id,omitempty"`
+	ReservedPercent *float64 `json:"reserved_percent,omitempty"` // parte do saldo reservada para a meta (padrão 100%)
+
+	// Aporte mensal programado (usado na previsão de fluxo de caixa)
+	ContributionAmount    *float64 `json:"contribution_amount,omitempty"`
+	ContributionDay       *int     `json:"contribution_day,omitempty"`
+	ContributionAccountID *int64   `json:"contribution_account_id,omitempty"`
+
+	// Previsão calculada a partir do histórico de aportes
+	MonthlyRequired     *float64   `json:"monthly_required,omitempty"`
+	MonthlyPace         float64    `json:"monthly_pace"`
+	ProjectedCompletion *time.Time `json:"projected_completion,omitempty"`
+	Status              string     `json:"status"` // completed, on_track, at_risk, no_deadline
+}
+
+type GoalContribution struct {
+	ID         int64     `json:"id"`
+	GoalID     int64     `json:"goal_id"`
+	AccountID  *int64    `json:"account_id"`
+	TransferID *int64    `json:"transfer_id,omitempty"`
+	Amount     float64   `json:"amount"`
+	Date       time.Time `json:"date"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ExchangeRate: 1 unidade de Currency vale Rate unidades de QuoteCurrency na data
+type ExchangeRate struct {
+	ID            int64     `json:"id"`
+	Currency      string    `json:"currency"`
+	QuoteCurrency string    `json:"quote_currency"`
+	Date          time.Time `json:"date"`
+	Rate          float64   `json:"rate"`
 }
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+type TaxDeductibleItem struct {
+	Date        time.Time `json:"date"`
+	Description string    `json:"description"`
+	Amount      float64   `json:"amount"`
+}
+
+type TaxDeductibleGroup struct {
+	Kind       string              `json:"kind"` // saude, educacao, previdencia
+	Label      string              `json:"label"`
+	Total      float64             `json:"total"`
+	Limit      *float64            `json:"limit,omitempty"` // teto de dedução, quando existe
+	Deductible float64             `json:"deductible"`      // total limitado ao teto
+	Items      []TaxDeductibleItem `json:"items"`
+}
+
+type TaxIncomeSource struct {
+	Source string  `json:"source"`
+	Total  float64 `json:"total"`
+	Count  int     `json:"count"`
+}
+
+type TaxAssetBalance struct {
+	AccountID       int64   `json:"account_id"`
+	Name            string  `json:"name"`
+	Type            string  `json:"type"`
+	PreviousBalance float64 `json:"previous_balance"` // 31/12 do ano anterior
+	Balance         float64 `json:"balance"`          // 31/12 do ano
+}
+
+type TaxReport struct {
+	Year            int                  `json:"year"`
+	Deductibles     []TaxDeductibleGroup `json:"deductibles"`
+	TotalDeductible float64              `json:"total_deductible"`
+	Incomes         []TaxIncomeSource    `json:"incomes"`
+	TotalIncome     float64              `json:"total_income"`
+	Assets          []TaxAssetBalance    `json:"assets"`
+}
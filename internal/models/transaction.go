@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Transaction é a visão unificada de gastos e rendas usada na busca
+type Transaction struct {
+	Kind          string    `json:"kind"` // expense, income
+	ID            int64     `json:"id"`
+	Description   string    `json:"description"`
+	Amount        float64   `json:"amount"`
+	Currency      string    `json:"currency"`
+	Category      *string   `json:"category,omitempty"`
+	Group         *string   `json:"group,omitempty"`
+	PaymentMethod *string   `json:"payment_method,omitempty"`
+	Date          time.Time `json:"date"`
+	AccountID     *int64    `json:"account_id"`
+	Tags          []string  `json:"tags"`
+}
+
+// BatchResult é o resultado de uma operação do lote de transações
+type BatchResult struct {
+	Index  int         `json:"index"` // posição da operação no lote
+	Type   string      `json:"type"`  // expense, income ou transfer
+	Action string      `json:"action"`
+	ID     int64       `json:"id,omitempty"`
+	Status int         `json:"status"`
+	Error  string      `json:"error,omitempty"`
+	Data   interface{} `json:"data,omitempty"` // registro criado
+}
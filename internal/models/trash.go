@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TrashItem é um gasto, renda, meta ou conta excluído que ainda pode ser restaurado
+type TrashItem struct {
+	Type        string     `json:"type"` // expense, income, goal ou account
+	ID          int64      `json:"id"`
+	Description string     `json:"description"`    // descrição do lançamento ou nome da meta/conta
+	Amount      float64    `json:"amount"`         // valor, valor alvo da meta ou saldo da conta
+	Date        *time.Time `json:"date,omitempty"` // data do lançamento ou prazo da meta
+	Currency    string     `json:"currency"`
+	DeletedAt   time.Time  `json:"deleted_at"`
+	PurgeAt     time.Time  `json:"purge_at"` // quando será apagado de vez
+}
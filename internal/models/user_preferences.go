@@ -6,6 +6,7 @@ type UserPreferences struct {
 	ExpensesPercent      float64 `json:"expenses_percent"`
 	EntertainmentPercent float64 `json:"entertainment_percent"`
 	InvestmentPercent    float64 `json:"investment_percent"`
+	BaseCurrency         string  `json:"base_currency"` // moeda dos resumos e do patrimônio
 	CreatedAt            string  `json:"created_at"`
 	UpdatedAt            string  `json:"updated_at"`
 }
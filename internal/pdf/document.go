@@ -0,0 +1,164 @@
+// Package pdf gera documentos PDF simples (somente texto) sem dependências externas.
+// Usa as fontes padrão Helvetica e Courier com WinAnsiEncoding, o que cobre os
+// acentos do português.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pageWidth  = 595.0 // A4 em pontos
+	pageHeight = 842.0
+	margin     = 50.0
+)
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontMono    = "F3"
+)
+
+type line struct {
+	font string
+	size float64
+	text string
+}
+
+// Document acumula linhas de texto e quebra páginas automaticamente
+type Document struct {
+	pages [][]line
+	y     float64
+}
+
+func New() *Document {
+	d := &Document{}
+	d.newPage()
+	return d
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, []line{})
+	d.y = pageHeight - margin
+}
+
+func (d *Document) add(font string, size float64, text string) {
+	height := size * 1.4
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], line{font: font, size: size, text: text})
+}
+
+// Title escreve um título em negrito
+func (d *Document) Title(text string) { d.add(fontBold, 16, text) }
+
+// Heading escreve um subtítulo em negrito
+func (d *Document) Heading(text string) { d.add(fontBold, 12, text) }
+
+// Text escreve uma linha de texto comum
+func (d *Document) Text(text string) { d.add(fontRegular, 10, text) }
+
+// Row escreve colunas em fonte monoespaçada, alinhadas pelas larguras em caracteres.
+// Larguras negativas alinham o texto à direita.
+func (d *Document) Row(cols []string, widths []int) {
+	var b strings.Builder
+	for i, col := range cols {
+		w := 0
+		if i < len(widths) {
+			w = widths[i]
+		}
+		runes := []rune(col)
+		abs := w
+		if abs < 0 {
+			abs = -abs
+		}
+		if abs > 0 && len(runes) > abs {
+			runes = runes[:abs]
+		}
+		pad := strings.Repeat(" ", abs-len(runes))
+		if w < 0 {
+			b.WriteString(pad + string(runes))
+		} else {
+			b.WriteString(string(runes) + pad)
+		}
+		b.WriteString(" ")
+	}
+	d.add(fontMono, 9, strings.TrimRight(b.String(), " "))
+}
+
+// Blank insere uma linha em branco
+func (d *Document) Blank() { d.add(fontRegular, 10, "") }
+
+// Bytes serializa o documento no formato PDF 1.4
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// 1: catálogo, 2: árvore de páginas, 3-5: fontes, depois conteúdo+página para cada página
+	firstPage := 6
+	var kids []string
+	for i := range d.pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", firstPage+i*2+1))
+	}
+
+	writeObj("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	writeObj("<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>")
+
+	for _, page := range d.pages {
+		var content bytes.Buffer
+		y := pageHeight - margin
+		for _, l := range page {
+			y -= l.size * 1.4
+			if l.text == "" {
+				continue
+			}
+			fmt.Fprintf(&content, "BT /%s %.1f Tf %.1f %.1f Td (%s) Tj ET\n", l.font, l.size, margin, y, encodeText(l.text))
+		}
+		writeObj(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+		writeObj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Contents %d 0 R /Resources << /Font << /F1 3 0 R /F2 4 0 R /F3 5 0 R >> >> >>",
+			pageWidth, pageHeight, len(offsets)))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+// encodeText converte para Latin-1 (compatível com WinAnsi nos acentos) e escapa
+// os caracteres especiais de strings PDF
+func encodeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(byte(r))
+		case r < 32:
+			b.WriteByte(' ')
+		case r < 256:
+			b.WriteByte(byte(r))
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
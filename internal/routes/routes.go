@@ -1,95 +1,281 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 
+	"github.com/edgar-lins/controle-financeiro/internal/config"
+	"github.com/edgar-lins/controle-financeiro/internal/docs"
 	"github.com/edgar-lins/controle-financeiro/internal/handlers"
+	"github.com/edgar-lins/controle-financeiro/internal/metrics"
 	"github.com/edgar-lins/controle-financeiro/internal/middleware"
+	"github.com/edgar-lins/controle-financeiro/internal/storage"
 )
 
-func SetupRoutes(db *sql.DB) {
+// SetupRoutes monta as rotas da API: os recursos em /v1 (padrões "MÉTODO /caminho/{id}"
+// do ServeMux, que responde 405 com Allow para métodos não registrados) e as rotas
+// antigas, mantidas como aliases obsoletos
+func SetupRoutes(ctx context.Context, cfg *config.Config, db *sql.DB) *http.ServeMux {
+	attachmentStorage := storage.NewLocal(cfg.AttachmentsDir)
+	trashRetention := cfg.TrashRetention
+	auth := middleware.Auth{Secret: cfg.JWTSecret}
+
 	expenseHandler := handlers.ExpenseHandler{DB: db}
 	summaryHandler := handlers.SummaryHandler{DB: db}
 	incomeHandler := handlers.IncomeHandler{DB: db}
-	authHandler := handlers.AuthHandler{DB: db}
+	authHandler := handlers.AuthHandler{DB: db, JWTSecret: cfg.JWTSecret, TokenTTL: cfg.TokenTTL}
 	accountHandler := handlers.AccountHandler{DB: db}
 	goalHandler := handlers.GoalHandler{DB: db}
 	migrationHandler := handlers.MigrationHandler{DB: db}
+	netWorthHandler := handlers.NetWorthHandler{DB: db}
+	forecastHandler := handlers.ForecastHandler{DB: db}
+	taxReportHandler := handlers.TaxReportHandler{DB: db}
+	tagHandler := handlers.TagHandler{DB: db}
+	transactionHandler := handlers.TransactionHandler{DB: db}
+	attachmentHandler := handlers.AttachmentHandler{DB: db, Storage: attachmentStorage}
+	exchangeRateHandler := handlers.ExchangeRateHandler{DB: db}
+	investmentHandler := handlers.InvestmentHandler{DB: db}
+	loanHandler := handlers.LoanHandler{DB: db}
+	auditHandler := handlers.AuditHandler{DB: db}
+	healthHandler := handlers.HealthHandler{DB: db}
+	trashHandler := handlers.TrashHandler{DB: db, Storage: attachmentStorage, Retention: trashRetention}
+
+	// Limpeza diária dos itens que passaram do prazo na lixeira
+	handlers.StartTrashPurge(ctx, db, attachmentStorage, trashRetention)
+
+	mux := http.NewServeMux()
 
 	// Auth endpoints (public)
-	http.HandleFunc("/auth/signup", authHandler.Signup)
-	http.HandleFunc("/auth/login", authHandler.Login)
-
-	http.HandleFunc("/expenses", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			middleware.WithAuth(expenseHandler.CreateExpense)(w, r)
-		} else if r.Method == http.MethodGet {
-			middleware.WithAuth(expenseHandler.GetExpenses)(w, r)
-		} else {
-			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		}
-	})
-
-	http.HandleFunc("/incomes", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			middleware.WithAuth(incomeHandler.CreateIncome)(w, r)
-		} else if r.Method == http.MethodGet {
-			middleware.WithAuth(incomeHandler.GetIncomes)(w, r)
-		} else {
-			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		}
-	})
-
-	http.HandleFunc("/summary", middleware.WithAuth(summaryHandler.GetSummary))
-	http.HandleFunc("/summary/history", middleware.WithAuth(summaryHandler.GetMonthlyHistory))
-	http.HandleFunc("/summary/breakdown", middleware.WithAuth(summaryHandler.GetExpenseBreakdown))
-	http.HandleFunc("/expenses/delete", middleware.WithAuth(expenseHandler.DeleteExpense))
-	http.HandleFunc("/expenses/update", middleware.WithAuth(expenseHandler.UpdateExpense))
-	http.HandleFunc("/incomes/delete", middleware.WithAuth(incomeHandler.DeleteIncome))
-	http.HandleFunc("/incomes/update", middleware.WithAuth(incomeHandler.UpdateIncome))
-
-	// Premium features - Accounts
-	http.HandleFunc("/accounts", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			middleware.WithAuth(accountHandler.CreateAccount)(w, r)
-		} else if r.Method == http.MethodGet {
-			middleware.WithAuth(accountHandler.GetAccounts)(w, r)
-		} else {
-			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		}
-	})
-	http.HandleFunc("/accounts/transfer", middleware.WithAuth(accountHandler.TransferFunds))
-	http.HandleFunc("/accounts/delete", middleware.WithAuth(accountHandler.DeleteAccount))
-	http.HandleFunc("/accounts/update", middleware.WithAuth(accountHandler.UpdateAccount))
+	mux.HandleFunc("POST /v1/auth/signup", authHandler.Signup)
+	mux.HandleFunc("POST /v1/auth/login", authHandler.Login)
+
+	// API docs (public): especificação OpenAPI e página de documentação
+	mux.HandleFunc("GET /openapi.json", docs.Spec)
+	mux.HandleFunc("GET /docs", docs.Page)
+
+	// Health checks (public): liveness e readiness
+	mux.HandleFunc("GET /healthz", healthHandler.Healthz)
+	mux.HandleFunc("GET /readyz", healthHandler.Readyz)
+
+	// Métricas do Prometheus, protegidas por METRICS_TOKEN
+	mux.Handle("GET /metrics", metrics.Handler(cfg.MetricsToken))
+
+	// Expenses
+	mux.HandleFunc("GET /v1/expenses", auth.WithAuth(expenseHandler.GetExpenses))
+	mux.HandleFunc("POST /v1/expenses", auth.WithAuth(expenseHandler.CreateExpense))
+	mux.HandleFunc("GET /v1/expenses/{id}", auth.WithAuth(expenseHandler.GetExpenses))
+	mux.HandleFunc("PUT /v1/expenses/{id}", auth.WithAuth(expenseHandler.UpdateExpense))
+	mux.HandleFunc("PATCH /v1/expenses/{id}", auth.WithAuth(expenseHandler.PatchExpense))
+	mux.HandleFunc("DELETE /v1/expenses/{id}", auth.WithAuth(expenseHandler.DeleteExpense))
+
+	// Incomes
+	mux.HandleFunc("GET /v1/incomes", auth.WithAuth(incomeHandler.GetIncomes))
+	mux.HandleFunc("POST /v1/incomes", auth.WithAuth(incomeHandler.CreateIncome))
+	mux.HandleFunc("GET /v1/incomes/{id}", auth.WithAuth(incomeHandler.GetIncomes))
+	mux.HandleFunc("PUT /v1/incomes/{id}", auth.WithAuth(incomeHandler.UpdateIncome))
+	mux.HandleFunc("PATCH /v1/incomes/{id}", auth.WithAuth(incomeHandler.PatchIncome))
+	mux.HandleFunc("DELETE /v1/incomes/{id}", auth.WithAuth(incomeHandler.DeleteIncome))
+
+	// Summary
+	mux.HandleFunc("GET /v1/summary", auth.WithAuth(summaryHandler.GetSummary))
+	mux.HandleFunc("GET /v1/summary/history", auth.WithAuth(summaryHandler.GetMonthlyHistory))
+	mux.HandleFunc("GET /v1/summary/breakdown", auth.WithAuth(summaryHandler.GetExpenseBreakdown))
+	mux.HandleFunc("GET /v1/summary/breakdown/tags", auth.WithAuth(summaryHandler.GetTagBreakdown))
+
+	// Premium features - Accounts and transfers
+	mux.HandleFunc("GET /v1/accounts", auth.WithAuth(accountHandler.GetAccounts))
+	mux.HandleFunc("POST /v1/accounts", auth.WithAuth(accountHandler.CreateAccount))
+	mux.HandleFunc("GET /v1/accounts/{id}", auth.WithAuth(accountHandler.GetAccounts))
+	mux.HandleFunc("PUT /v1/accounts/{id}", auth.WithAuth(accountHandler.UpdateAccount))
+	mux.HandleFunc("PATCH /v1/accounts/{id}", auth.WithAuth(accountHandler.PatchAccount))
+	mux.HandleFunc("DELETE /v1/accounts/{id}", auth.WithAuth(accountHandler.DeleteAccount))
+	mux.HandleFunc("GET /v1/accounts/{id}/transfers", auth.WithAuth(accountHandler.GetTransfers))
+	mux.HandleFunc("GET /v1/transfers", auth.WithAuth(accountHandler.GetTransfers))
+	mux.HandleFunc("POST /v1/transfers", auth.WithAuth(accountHandler.TransferFunds))
 
 	// Premium features - Goals
-	http.HandleFunc("/goals", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodPost {
-			middleware.WithAuth(goalHandler.CreateGoal)(w, r)
-		} else if r.Method == http.MethodGet {
-			middleware.WithAuth(goalHandler.GetGoals)(w, r)
-		} else {
-			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		}
-	})
-	http.HandleFunc("/goals/delete", middleware.WithAuth(goalHandler.DeleteGoal))
-	http.HandleFunc("/goals/update", middleware.WithAuth(goalHandler.UpdateGoal))
-	http.HandleFunc("/goals/add-money", middleware.WithAuth(goalHandler.AddMoneyToGoal))
+	mux.HandleFunc("GET /v1/goals", auth.WithAuth(goalHandler.GetGoals))
+	mux.HandleFunc("POST /v1/goals", auth.WithAuth(goalHandler.CreateGoal))
+	mux.HandleFunc("GET /v1/goals/{id}", auth.WithAuth(goalHandler.GetGoals))
+	mux.HandleFunc("PUT /v1/goals/{id}", auth.WithAuth(goalHandler.UpdateGoal))
+	mux.HandleFunc("PATCH /v1/goals/{id}", auth.WithAuth(goalHandler.PatchGoal))
+	mux.HandleFunc("DELETE /v1/goals/{id}", auth.WithAuth(goalHandler.DeleteGoal))
+	mux.HandleFunc("GET /v1/goals/{id}/contributions", auth.WithAuth(goalHandler.GetGoalContributions))
+	mux.HandleFunc("POST /v1/goals/{id}/contributions", auth.WithAuth(goalHandler.AddMoneyToGoal))
+
+	// Premium features - Net worth history
+	mux.HandleFunc("GET /v1/networth/history", auth.WithAuth(netWorthHandler.GetNetWorthHistory))
+	mux.HandleFunc("POST /v1/networth/rebuild", auth.WithAuth(netWorthHandler.RebuildSnapshots))
+
+	// Premium features - Cash flow forecast
+	mux.HandleFunc("GET /v1/forecast", auth.WithAuth(forecastHandler.GetCashFlowForecast))
+
+	// Tags
+	mux.HandleFunc("GET /v1/tags", auth.WithAuth(tagHandler.GetTags))
+	mux.HandleFunc("POST /v1/tags", auth.WithAuth(tagHandler.CreateTag))
+	mux.HandleFunc("PUT /v1/tags/{id}", auth.WithAuth(tagHandler.UpdateTag))
+	mux.HandleFunc("DELETE /v1/tags/{id}", auth.WithAuth(tagHandler.DeleteTag))
+
+	// Attachments (nota fiscal e comprovantes de gastos, rendas e metas: ?type=&id=)
+	mux.HandleFunc("GET /v1/attachments", auth.WithAuth(attachmentHandler.GetAttachments))
+	mux.HandleFunc("POST /v1/attachments", auth.WithAuth(attachmentHandler.UploadAttachment))
+	mux.HandleFunc("GET /v1/attachments/usage", auth.WithAuth(attachmentHandler.GetAttachmentUsage))
+	mux.HandleFunc("GET /v1/attachments/{id}", auth.WithAuth(attachmentHandler.DownloadAttachment))
+	mux.HandleFunc("DELETE /v1/attachments/{id}", auth.WithAuth(attachmentHandler.DeleteAttachment))
+
+	// Investments (ativos das contas de investimento)
+	mux.HandleFunc("GET /v1/investments/holdings", auth.WithAuth(investmentHandler.GetHoldings))
+	mux.HandleFunc("POST /v1/investments/holdings", auth.WithAuth(investmentHandler.CreateHolding))
+	mux.HandleFunc("PUT /v1/investments/holdings/{id}", auth.WithAuth(investmentHandler.UpdateHolding))
+	mux.HandleFunc("DELETE /v1/investments/holdings/{id}", auth.WithAuth(investmentHandler.DeleteHolding))
+	mux.HandleFunc("GET /v1/investments/operations", auth.WithAuth(investmentHandler.GetOperations))
+	mux.HandleFunc("POST /v1/investments/operations", auth.WithAuth(investmentHandler.CreateOperation))
+	mux.HandleFunc("DELETE /v1/investments/operations/{id}", auth.WithAuth(investmentHandler.DeleteOperation))
+	mux.HandleFunc("GET /v1/investments/quotes", auth.WithAuth(investmentHandler.GetQuotes))
+	mux.HandleFunc("POST /v1/investments/quotes", auth.WithAuth(investmentHandler.CreateQuote))
+	mux.HandleFunc("POST /v1/investments/quotes/import", auth.WithAuth(investmentHandler.ImportQuotes))
+	mux.HandleFunc("DELETE /v1/investments/quotes/{id}", auth.WithAuth(investmentHandler.DeleteQuote))
+	mux.HandleFunc("GET /v1/investments/portfolio", auth.WithAuth(investmentHandler.GetPortfolio))
+
+	// Loans (empréstimos e financiamentos)
+	mux.HandleFunc("GET /v1/loans", auth.WithAuth(loanHandler.GetLoans))
+	mux.HandleFunc("POST /v1/loans", auth.WithAuth(loanHandler.CreateLoan))
+	mux.HandleFunc("PUT /v1/loans/{id}", auth.WithAuth(loanHandler.UpdateLoan))
+	mux.HandleFunc("DELETE /v1/loans/{id}", auth.WithAuth(loanHandler.DeleteLoan))
+	mux.HandleFunc("GET /v1/loans/{id}/schedule", auth.WithAuth(loanHandler.GetLoanSchedule))
+	mux.HandleFunc("POST /v1/loans/{id}/payments", auth.WithAuth(loanHandler.CreateLoanPayment))
+	mux.HandleFunc("DELETE /v1/loans/{loan}/payments/{id}", auth.WithAuth(loanHandler.DeleteLoanPayment))
+
+	// Audit log (histórico de alterações)
+	mux.HandleFunc("GET /v1/audit", auth.WithAuth(auditHandler.GetAuditLog))
+
+	// Trash (gastos, rendas, metas e contas excluídos)
+	mux.HandleFunc("GET /v1/trash", auth.WithAuth(trashHandler.GetTrash))
+	mux.HandleFunc("POST /v1/trash/{type}/{id}/restore", auth.WithAuth(trashHandler.RestoreTrash))
+
+	// Exchange rates (cotações para contas em outras moedas)
+	mux.HandleFunc("GET /v1/exchange-rates", auth.WithAuth(exchangeRateHandler.GetExchangeRates))
+	mux.HandleFunc("POST /v1/exchange-rates", auth.WithAuth(exchangeRateHandler.CreateExchangeRate))
+	mux.HandleFunc("POST /v1/exchange-rates/import", auth.WithAuth(exchangeRateHandler.ImportExchangeRates))
+	mux.HandleFunc("DELETE /v1/exchange-rates/{id}", auth.WithAuth(exchangeRateHandler.DeleteExchangeRate))
+
+	// Transactions (gastos e rendas juntos)
+	mux.HandleFunc("GET /v1/transactions/search", auth.WithAuth(transactionHandler.SearchTransactions))
+	mux.HandleFunc("POST /v1/transactions/batch", auth.WithAuth(transactionHandler.BatchTransactions))
+
+	// Reports
+	mux.HandleFunc("GET /v1/reports/irpf", auth.WithAuth(taxReportHandler.GetTaxReport))
 
 	// User Preferences
-	http.HandleFunc("/preferences", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			middleware.WithAuth(handlers.GetUserPreferences(db))(w, r)
-		} else if r.Method == http.MethodPut {
-			middleware.WithAuth(handlers.UpdateUserPreferences(db))(w, r)
-		} else {
-			http.Error(w, "Método não permitido", http.StatusMethodNotAllowed)
-		}
-	})
+	mux.HandleFunc("GET /v1/preferences", auth.WithAuth(handlers.GetUserPreferences(db)))
+	mux.HandleFunc("PUT /v1/preferences", auth.WithAuth(handlers.UpdateUserPreferences(db)))
 
 	// Migration endpoints
-	http.HandleFunc("/migration/check", middleware.WithAuth(migrationHandler.CheckUnlinkedTransactions))
-	http.HandleFunc("/migration/migrate", middleware.WithAuth(migrationHandler.MigrateUnlinkedTransactions))
+	mux.HandleFunc("GET /v1/migration/check", auth.WithAuth(migrationHandler.CheckUnlinkedTransactions))
+	mux.HandleFunc("POST /v1/migration/migrate", auth.WithAuth(migrationHandler.MigrateUnlinkedTransactions))
+
+	// Rotas antigas (sem /v1, verbo no caminho e ?id=): obsoletas, respondem com os
+	// cabeçalhos Deprecation e Link apontando para a rota nova
+	legacy := func(pattern, successor string, handler http.HandlerFunc) {
+		mux.HandleFunc(pattern, deprecated(successor, handler))
+	}
+
+	legacy("/auth/signup", "/v1/auth/signup", authHandler.Signup)
+	legacy("/auth/login", "/v1/auth/login", authHandler.Login)
+
+	legacy("GET /expenses", "/v1/expenses", auth.WithAuth(expenseHandler.GetExpenses))
+	legacy("POST /expenses", "/v1/expenses", auth.WithAuth(expenseHandler.CreateExpense))
+	legacy("/expenses/update", "/v1/expenses/{id}", auth.WithAuth(expenseHandler.UpdateExpense))
+	legacy("/expenses/delete", "/v1/expenses/{id}", auth.WithAuth(expenseHandler.DeleteExpense))
+
+	legacy("GET /incomes", "/v1/incomes", auth.WithAuth(incomeHandler.GetIncomes))
+	legacy("POST /incomes", "/v1/incomes", auth.WithAuth(incomeHandler.CreateIncome))
+	legacy("/incomes/update", "/v1/incomes/{id}", auth.WithAuth(incomeHandler.UpdateIncome))
+	legacy("/incomes/delete", "/v1/incomes/{id}", auth.WithAuth(incomeHandler.DeleteIncome))
+
+	legacy("/summary", "/v1/summary", auth.WithAuth(summaryHandler.GetSummary))
+	legacy("/summary/history", "/v1/summary/history", auth.WithAuth(summaryHandler.GetMonthlyHistory))
+	legacy("/summary/breakdown", "/v1/summary/breakdown", auth.WithAuth(summaryHandler.GetExpenseBreakdown))
+	legacy("/summary/breakdown/tags", "/v1/summary/breakdown/tags", auth.WithAuth(summaryHandler.GetTagBreakdown))
+
+	legacy("GET /accounts", "/v1/accounts", auth.WithAuth(accountHandler.GetAccounts))
+	legacy("POST /accounts", "/v1/accounts", auth.WithAuth(accountHandler.CreateAccount))
+	legacy("/accounts/update", "/v1/accounts/{id}", auth.WithAuth(accountHandler.UpdateAccount))
+	legacy("/accounts/delete", "/v1/accounts/{id}", auth.WithAuth(accountHandler.DeleteAccount))
+	legacy("/accounts/transfer", "/v1/transfers", auth.WithAuth(accountHandler.TransferFunds))
+	legacy("/accounts/transfers", "/v1/transfers", auth.WithAuth(accountHandler.GetTransfers))
+
+	legacy("GET /goals", "/v1/goals", auth.WithAuth(goalHandler.GetGoals))
+	legacy("POST /goals", "/v1/goals", auth.WithAuth(goalHandler.CreateGoal))
+	legacy("/goals/update", "/v1/goals/{id}", auth.WithAuth(goalHandler.UpdateGoal))
+	legacy("/goals/delete", "/v1/goals/{id}", auth.WithAuth(goalHandler.DeleteGoal))
+	legacy("/goals/add-money", "/v1/goals/{id}/contributions", auth.WithAuth(goalHandler.AddMoneyToGoal))
+	legacy("/goals/contributions", "/v1/goals/{id}/contributions", auth.WithAuth(goalHandler.GetGoalContributions))
+
+	legacy("/networth/history", "/v1/networth/history", auth.WithAuth(netWorthHandler.GetNetWorthHistory))
+	legacy("/networth/rebuild", "/v1/networth/rebuild", auth.WithAuth(netWorthHandler.RebuildSnapshots))
+	legacy("/forecast", "/v1/forecast", auth.WithAuth(forecastHandler.GetCashFlowForecast))
+
+	legacy("GET /tags", "/v1/tags", auth.WithAuth(tagHandler.GetTags))
+	legacy("POST /tags", "/v1/tags", auth.WithAuth(tagHandler.CreateTag))
+	legacy("/tags/update", "/v1/tags/{id}", auth.WithAuth(tagHandler.UpdateTag))
+	legacy("/tags/delete", "/v1/tags/{id}", auth.WithAuth(tagHandler.DeleteTag))
+
+	legacy("GET /attachments", "/v1/attachments", auth.WithAuth(attachmentHandler.GetAttachments))
+	legacy("POST /attachments", "/v1/attachments", auth.WithAuth(attachmentHandler.UploadAttachment))
+	legacy("/attachments/download", "/v1/attachments/{id}", auth.WithAuth(attachmentHandler.DownloadAttachment))
+	legacy("/attachments/delete", "/v1/attachments/{id}", auth.WithAuth(attachmentHandler.DeleteAttachment))
+	legacy("/attachments/usage", "/v1/attachments/usage", auth.WithAuth(attachmentHandler.GetAttachmentUsage))
+
+	legacy("GET /investments/holdings", "/v1/investments/holdings", auth.WithAuth(investmentHandler.GetHoldings))
+	legacy("POST /investments/holdings", "/v1/investments/holdings", auth.WithAuth(investmentHandler.CreateHolding))
+	legacy("/investments/holdings/update", "/v1/investments/holdings/{id}", auth.WithAuth(investmentHandler.UpdateHolding))
+	legacy("/investments/holdings/delete", "/v1/investments/holdings/{id}", auth.WithAuth(investmentHandler.DeleteHolding))
+	legacy("GET /investments/operations", "/v1/investments/operations", auth.WithAuth(investmentHandler.GetOperations))
+	legacy("POST /investments/operations", "/v1/investments/operations", auth.WithAuth(investmentHandler.CreateOperation))
+	legacy("/investments/operations/delete", "/v1/investments/operations/{id}", auth.WithAuth(investmentHandler.DeleteOperation))
+	legacy("GET /investments/quotes", "/v1/investments/quotes", auth.WithAuth(investmentHandler.GetQuotes))
+	legacy("POST /investments/quotes", "/v1/investments/quotes", auth.WithAuth(investmentHandler.CreateQuote))
+	legacy("/investments/quotes/import", "/v1/investments/quotes/import", auth.WithAuth(investmentHandler.ImportQuotes))
+	legacy("/investments/quotes/delete", "/v1/investments/quotes/{id}", auth.WithAuth(investmentHandler.DeleteQuote))
+	legacy("/investments/portfolio", "/v1/investments/portfolio", auth.WithAuth(investmentHandler.GetPortfolio))
+
+	legacy("GET /loans", "/v1/loans", auth.WithAuth(loanHandler.GetLoans))
+	legacy("POST /loans", "/v1/loans", auth.WithAuth(loanHandler.CreateLoan))
+	legacy("/loans/update", "/v1/loans/{id}", auth.WithAuth(loanHandler.UpdateLoan))
+	legacy("/loans/delete", "/v1/loans/{id}", auth.WithAuth(loanHandler.DeleteLoan))
+	legacy("/loans/schedule", "/v1/loans/{id}/schedule", auth.WithAuth(loanHandler.GetLoanSchedule))
+	legacy("/loans/payments", "/v1/loans/{id}/payments", auth.WithAuth(loanHandler.CreateLoanPayment))
+	legacy("/loans/payments/delete", "/v1/loans/{loan}/payments/{id}", auth.WithAuth(loanHandler.DeleteLoanPayment))
+
+	legacy("/audit", "/v1/audit", auth.WithAuth(auditHandler.GetAuditLog))
+	legacy("/trash", "/v1/trash", auth.WithAuth(trashHandler.GetTrash))
+	legacy("/trash/restore", "/v1/trash/{type}/{id}/restore", auth.WithAuth(trashHandler.RestoreTrash))
+
+	legacy("GET /exchange-rates", "/v1/exchange-rates", auth.WithAuth(exchangeRateHandler.GetExchangeRates))
+	legacy("POST /exchange-rates", "/v1/exchange-rates", auth.WithAuth(exchangeRateHandler.CreateExchangeRate))
+	legacy("/exchange-rates/import", "/v1/exchange-rates/import", auth.WithAuth(exchangeRateHandler.ImportExchangeRates))
+	legacy("/exchange-rates/delete", "/v1/exchange-rates/{id}", auth.WithAuth(exchangeRateHandler.DeleteExchangeRate))
+
+	legacy("/transactions/search", "/v1/transactions/search", auth.WithAuth(transactionHandler.SearchTransactions))
+	legacy("/transactions/batch", "/v1/transactions/batch", auth.WithAuth(transactionHandler.BatchTransactions))
+	legacy("/reports/irpf", "/v1/reports/irpf", auth.WithAuth(taxReportHandler.GetTaxReport))
+
+	legacy("GET /preferences", "/v1/preferences", auth.WithAuth(handlers.GetUserPreferences(db)))
+	legacy("PUT /preferences", "/v1/preferences", auth.WithAuth(handlers.UpdateUserPreferences(db)))
+
+	legacy("/migration/check", "/v1/migration/check", auth.WithAuth(migrationHandler.CheckUnlinkedTransactions))
+	legacy("/migration/migrate", "/v1/migration/migrate", auth.WithAuth(migrationHandler.MigrateUnlinkedTransactions))
+
+	return mux
+}
 
+// deprecated marca a resposta de uma rota antiga como obsoleta (RFC 8594) e indica a sucessora
+func deprecated(successor string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		next(w, r)
+	}
 }
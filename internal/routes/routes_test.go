@@ -0,0 +1,134 @@
+package routes
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/edgar-lins/controle-financeiro/internal/docs"
+)
+
+// route é um padrão registrado em SetupRoutes; method vazio aceita qualquer método
+type route struct {
+	method, path string
+	legacy       bool
+}
+
+// registeredRoutes lê em routes.go os padrões registrados no mux (mux.HandleFunc e
+// mux.Handle) e pelas rotas antigas (legacy)
+func registeredRoutes(t *testing.T) []route {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "routes.go", nil, 0)
+	if err != nil {
+		t.Fatalf("erro ao ler routes.go: %v", err)
+	}
+
+	var routes []route
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || len(call.Args) == 0 {
+			return true
+		}
+		legacy := false
+		switch fun := call.Fun.(type) {
+		case *ast.Ident:
+			if fun.Name != "legacy" {
+				return true
+			}
+			legacy = true
+		case *ast.SelectorExpr:
+			x, ok := fun.X.(*ast.Ident)
+			if !ok || x.Name != "mux" || (fun.Sel.Name != "HandleFunc" && fun.Sel.Name != "Handle") {
+				return true
+			}
+		default:
+			return true
+		}
+		// O padrão dentro do próprio legacy não é literal e fica de fora
+		lit, ok := call.Args[0].(*ast.BasicLit)
+		if !ok || lit.Kind != token.STRING {
+			return true
+		}
+		pattern, _ := strconv.Unquote(lit.Value)
+		method, path, found := strings.Cut(pattern, " ")
+		if !found {
+			method, path = "", pattern
+		}
+		if method == "" && !legacy {
+			t.Errorf("rota %s sem método no padrão", path)
+		}
+		routes = append(routes, route{method: method, path: path, legacy: legacy})
+		return true
+	})
+	return routes
+}
+
+// documentedRoutes lê as operações da especificação servida em /openapi.json, por
+// caminho e método, indicando se estão marcadas como obsoletas
+func documentedRoutes(t *testing.T) map[string]map[string]bool {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	docs.Spec(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var spec struct {
+		Paths map[string]map[string]struct {
+			Deprecated bool `json:"deprecated"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("openapi.json inválido: %v", err)
+	}
+
+	routes := map[string]map[string]bool{}
+	for path, operations := range spec.Paths {
+		routes[path] = map[string]bool{}
+		for method, operation := range operations {
+			routes[path][strings.ToUpper(method)] = operation.Deprecated
+		}
+	}
+	return routes
+}
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	registered := registeredRoutes(t)
+	documented := documentedRoutes(t)
+	if len(registered) == 0 {
+		t.Fatal("nenhuma rota encontrada em routes.go")
+	}
+
+	// Operações documentadas cobertas por algum registro (sem método cobre todas do caminho)
+	covered := map[string]bool{}
+	for _, r := range registered {
+		operations := documented[r.path]
+		if r.method != "" {
+			if _, ok := operations[r.method]; !ok {
+				t.Errorf("rota %s %s registrada em SetupRoutes não está em openapi.json", r.method, r.path)
+				continue
+			}
+			operations = map[string]bool{r.method: operations[r.method]}
+		} else if len(operations) == 0 {
+			t.Errorf("rota %s registrada em SetupRoutes não está em openapi.json", r.path)
+			continue
+		}
+		for method, deprecated := range operations {
+			covered[method+" "+r.path] = true
+			if deprecated != r.legacy {
+				t.Errorf("%s %s: deprecated = %v em openapi.json, esperado %v", method, r.path, deprecated, r.legacy)
+			}
+		}
+	}
+
+	for path, operations := range documented {
+		for method := range operations {
+			if !covered[method+" "+path] {
+				t.Errorf("openapi.json documenta %s %s, que não está registrada em SetupRoutes", method, path)
+			}
+		}
+	}
+}
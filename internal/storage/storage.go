@@ -0,0 +1,97 @@
+// Package storage guarda os arquivos anexados (notas fiscais, comprovantes).
+// Os handlers dependem só da interface Storage, então trocar o disco local por
+// um bucket (S3, GCS) não exige mudanças fora deste pacote.
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNotFound é retornado quando a chave não existe no armazenamento
+var ErrNotFound = errors.New("arquivo não encontrado")
+
+// Storage grava, lê e remove arquivos identificados por uma chave
+type Storage interface {
+	// Save grava o conteúdo de r na chave e retorna quantos bytes foram escritos
+	Save(key string, r io.Reader) (int64, error)
+	Open(key string) (io.ReadCloser, error)
+	Delete(key string) error
+}
+
+// Local guarda os arquivos em um diretório do disco
+type Local struct {
+	Dir string
+}
+
+// NewLocal cria o armazenamento local no diretório informado (padrão: data/attachments)
+func NewLocal(dir string) *Local {
+	if dir == "" {
+		dir = filepath.Join("data", "attachments")
+	}
+	return &Local{Dir: dir}
+}
+
+// path converte a chave em caminho dentro de Dir, recusando chaves que escapem dele
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if clean == "/" || strings.Contains(key, "..") {
+		return "", errors.New("chave inválida")
+	}
+	return filepath.Join(l.Dir, clean), nil
+}
+
+func (l *Local) Save(key string, r io.Reader) (int64, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	// Grava em arquivo temporário e renomeia, para nunca deixar um anexo pela metade
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return 0, err
+	}
+	return n, nil
+}
+
+func (l *Local) Open(key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (l *Local) Delete(key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+-- Histórico de aportes em metas, usado para projetar o ritmo de contribuição
+CREATE TABLE IF NOT EXISTS goal_contributions (
+    id SERIAL PRIMARY KEY,
+    goal_id INTEGER NOT NULL REFERENCES goals(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL,
+    amount NUMERIC(14,2) NOT NULL,
+    date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_goal_contributions_goal_date ON goal_contributions (goal_id, date);
+CREATE INDEX IF NOT EXISTS idx_goal_contributions_user_id ON goal_contributions (user_id);
+
+-- Rendimento anual opcional (%) para metas aplicadas
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS yield_rate DECIMAL(6,2);
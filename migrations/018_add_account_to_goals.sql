@@ -0,0 +1,10 @@
+-- Permite vincular uma meta a uma conta real (ex: poupança dedicada)
+-- Quando vinculada, o progresso é derivado do saldo da conta (ou de uma parte reservada dele)
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS account_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL;
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS reserved_percent DECIMAL(5,2)
+    CHECK (reserved_percent IS NULL OR (reserved_percent > 0 AND reserved_percent <= 100));
+
+CREATE INDEX IF NOT EXISTS idx_goals_account_id ON goals(account_id);
+
+-- Transferências para a conta da meta contam como aportes
+ALTER TABLE goal_contributions ADD COLUMN IF NOT EXISTS transfer_id INTEGER REFERENCES transfers(id) ON DELETE SET NULL;
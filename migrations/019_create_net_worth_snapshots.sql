@@ -0,0 +1,22 @@
+-- Snapshots diários do saldo de cada conta e do patrimônio líquido do usuário
+-- Preenchidos retroativamente a partir do histórico de transações
+CREATE TABLE IF NOT EXISTS account_balance_snapshots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    balance NUMERIC(14,2) NOT NULL,
+    UNIQUE (account_id, date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_balance_snapshots_user_date ON account_balance_snapshots (user_id, date);
+
+CREATE TABLE IF NOT EXISTS net_worth_snapshots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    assets NUMERIC(14,2) NOT NULL,
+    liabilities NUMERIC(14,2) NOT NULL,
+    net_worth NUMERIC(14,2) NOT NULL,
+    UNIQUE (user_id, date)
+);
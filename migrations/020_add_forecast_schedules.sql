@@ -0,0 +1,15 @@
+-- Dados de agenda usados na previsão de fluxo de caixa
+
+-- Fatura do cartão: dia de fechamento, dia de vencimento e conta que paga a fatura
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS statement_closing_day INTEGER
+    CHECK (statement_closing_day IS NULL OR statement_closing_day BETWEEN 1 AND 31);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS statement_due_day INTEGER
+    CHECK (statement_due_day IS NULL OR statement_due_day BETWEEN 1 AND 31);
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS payment_account_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL;
+
+-- Aporte mensal programado em metas
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS contribution_amount DECIMAL(14,2)
+    CHECK (contribution_amount IS NULL OR contribution_amount > 0);
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS contribution_day INTEGER
+    CHECK (contribution_day IS NULL OR contribution_day BETWEEN 1 AND 31);
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS contribution_account_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL;
@@ -0,0 +1,31 @@
+-- Tags livres do usuário (ex: viagem-2026, reembolsavel) ligadas a gastos, rendas e transferências
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_user_name ON tags (user_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS expense_tags (
+    expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (expense_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS income_tags (
+    income_id INTEGER NOT NULL REFERENCES incomes(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (income_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS transfer_tags (
+    transfer_id INTEGER NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+    PRIMARY KEY (transfer_id, tag_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_tags_tag_id ON expense_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_income_tags_tag_id ON income_tags (tag_id);
+CREATE INDEX IF NOT EXISTS idx_transfer_tags_tag_id ON transfer_tags (tag_id);
@@ -0,0 +1,2 @@
+-- Busca textual sem acentos (ex: "farmacia" encontra "Farmácia")
+CREATE EXTENSION IF NOT EXISTS unaccent;
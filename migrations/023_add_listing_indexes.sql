@@ -0,0 +1,3 @@
+-- Índices para a paginação por cursor (date DESC, id DESC) das listagens
+CREATE INDEX IF NOT EXISTS idx_expenses_user_date_id ON expenses (user_id, date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_incomes_user_date_id ON incomes (user_id, date DESC, id DESC);
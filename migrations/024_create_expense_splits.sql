@@ -0,0 +1,13 @@
+-- Divisão de um gasto em linhas com categoria, grupo e valor próprios
+-- (ex: compra do mercado = alimentação + limpeza + presente). A soma das linhas
+-- é igual ao valor do gasto; a conta continua sendo debitada uma vez pelo gasto.
+CREATE TABLE IF NOT EXISTS expense_splits (
+    id SERIAL PRIMARY KEY,
+    expense_id INTEGER NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    description TEXT,
+    category VARCHAR(50) NOT NULL,
+    "group" VARCHAR(20) NOT NULL CHECK ("group" IN ('essencial', 'lazer', 'investimento')),
+    amount NUMERIC(10,2) NOT NULL CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_expense_splits_expense ON expense_splits(expense_id);
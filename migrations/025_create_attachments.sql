@@ -0,0 +1,15 @@
+-- Anexos (nota fiscal, comprovantes) ligados a gastos, rendas e metas.
+-- O arquivo fica no armazenamento configurado (ATTACHMENTS_DIR); aqui só os metadados.
+CREATE TABLE IF NOT EXISTS attachments (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity_type VARCHAR(20) NOT NULL CHECK (entity_type IN ('expense', 'income', 'goal')),
+    entity_id INTEGER NOT NULL,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_entity ON attachments(user_id, entity_type, entity_id);
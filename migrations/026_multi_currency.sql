@@ -0,0 +1,58 @@
+-- Multi-moeda: moeda por conta e por transação, cotações cadastradas pelo usuário
+-- e conversão para a moeda base (preferências) na cotação da data da transação.
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- amount fica na moeda da transação; account_amount é o valor lançado na conta
+-- quando a moeda da transação difere da moeda da conta (ex: compra em USD no cartão em BRL)
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS account_amount NUMERIC(12,2);
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS account_amount NUMERIC(12,2);
+
+-- Valor creditado na conta de destino quando as contas têm moedas diferentes
+ALTER TABLE transfers ADD COLUMN IF NOT EXISTS to_amount NUMERIC(12,2);
+
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS base_currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- 1 unidade de currency = rate unidades de quote_currency na data
+CREATE TABLE IF NOT EXISTS exchange_rates (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    currency CHAR(3) NOT NULL,
+    quote_currency CHAR(3) NOT NULL,
+    date DATE NOT NULL,
+    rate NUMERIC(18,8) NOT NULL CHECK (rate > 0),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (user_id, currency, quote_currency, date),
+    CHECK (currency <> quote_currency)
+);
+
+CREATE INDEX IF NOT EXISTS idx_exchange_rates_pair ON exchange_rates(user_id, currency, quote_currency, date);
+
+-- Cotação de p_from para p_to: a mais recente até p_date (direta ou inversa);
+-- sem cotação anterior usa a primeira posterior. NULL quando o par não tem cotação.
+CREATE OR REPLACE FUNCTION exchange_rate(p_user INTEGER, p_from CHAR(3), p_to CHAR(3), p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN p_from = p_to THEN 1 ELSE (
+        SELECT rate FROM (
+            SELECT rate, date FROM exchange_rates
+            WHERE user_id = p_user AND currency = p_from AND quote_currency = p_to
+            UNION ALL
+            SELECT 1 / rate, date FROM exchange_rates
+            WHERE user_id = p_user AND currency = p_to AND quote_currency = p_from
+        ) r
+        ORDER BY (date > p_date), ABS(date - p_date)
+        LIMIT 1
+    ) END
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION convert_amount(p_user INTEGER, p_amount NUMERIC, p_from CHAR(3), p_to CHAR(3), p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT ROUND(p_amount * exchange_rate(p_user, p_from, p_to, p_date), 2)
+$$ LANGUAGE SQL STABLE;
+
+CREATE OR REPLACE FUNCTION user_base_currency(p_user INTEGER)
+RETURNS CHAR(3) AS $$
+    SELECT COALESCE((SELECT base_currency FROM user_preferences WHERE user_id = p_user), 'BRL')
+$$ LANGUAGE SQL STABLE;
@@ -0,0 +1,74 @@
+-- Carteira de investimentos: ativos (ações, FIIs, títulos de renda fixa...) dentro
+-- de contas do tipo investimento. O saldo da conta passa a ser o caixa disponível;
+-- compras, vendas e proventos movimentam esse caixa.
+CREATE TABLE IF NOT EXISTS holdings (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    account_id INTEGER NOT NULL REFERENCES accounts(id),
+    symbol VARCHAR(60) NOT NULL,           -- ticker (PETR4) ou nome do título (CDB Banco X 2027)
+    name VARCHAR(120),
+    asset_class VARCHAR(20) NOT NULL CHECK (asset_class IN ('acao', 'fii', 'etf', 'bdr', 'renda_fixa', 'tesouro', 'fundo', 'cripto', 'outro')),
+    -- Posição que já existia antes de registrar operações (não movimenta o caixa)
+    initial_quantity NUMERIC(20,8) NOT NULL DEFAULT 0 CHECK (initial_quantity >= 0),
+    initial_average_price NUMERIC(20,8) NOT NULL DEFAULT 0 CHECK (initial_average_price >= 0),
+    start_date DATE NOT NULL DEFAULT CURRENT_DATE,
+    -- Posição atual, recalculada a partir da inicial e das operações
+    quantity NUMERIC(20,8) NOT NULL DEFAULT 0,
+    average_price NUMERIC(20,8) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (account_id, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_holdings_user ON holdings(user_id);
+
+-- cash_amount é o efeito no caixa da conta (negativo nas compras)
+CREATE TABLE IF NOT EXISTS investment_operations (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    holding_id INTEGER NOT NULL REFERENCES holdings(id),
+    type VARCHAR(10) NOT NULL CHECK (type IN ('buy', 'sell', 'dividend')),
+    quantity NUMERIC(20,8) NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    price NUMERIC(20,8) NOT NULL DEFAULT 0 CHECK (price >= 0),
+    fees NUMERIC(12,2) NOT NULL DEFAULT 0 CHECK (fees >= 0),
+    cash_amount NUMERIC(12,2) NOT NULL,
+    realized_gain NUMERIC(12,2),           -- apenas vendas
+    description TEXT,
+    date DATE NOT NULL DEFAULT CURRENT_DATE,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_investment_operations_holding ON investment_operations(holding_id, date, id);
+CREATE INDEX IF NOT EXISTS idx_investment_operations_user ON investment_operations(user_id, date);
+
+-- Cotações dos ativos, por símbolo, cadastradas manualmente ou importadas
+CREATE TABLE IF NOT EXISTS asset_quotes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    symbol VARCHAR(60) NOT NULL,
+    date DATE NOT NULL,
+    price NUMERIC(20,8) NOT NULL CHECK (price > 0),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (user_id, symbol, date)
+);
+
+-- Preço de um ativo em p_date: a cotação mais recente até a data (NULL sem cotação)
+CREATE OR REPLACE FUNCTION asset_price(p_user INTEGER, p_symbol VARCHAR, p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT price FROM asset_quotes
+    WHERE user_id = p_user AND symbol = p_symbol AND date <= p_date
+    ORDER BY date DESC
+    LIMIT 1
+$$ LANGUAGE SQL STABLE;
+
+-- Quantidade de um ativo ao fim de p_date
+CREATE OR REPLACE FUNCTION holding_quantity(p_holding INTEGER, p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT (CASE WHEN h.start_date <= p_date THEN h.initial_quantity ELSE 0 END)
+        + COALESCE((
+            SELECT SUM(CASE o.type WHEN 'buy' THEN o.quantity WHEN 'sell' THEN -o.quantity ELSE 0 END)
+            FROM investment_operations o
+            WHERE o.holding_id = h.id AND o.date <= p_date
+        ), 0)
+    FROM holdings h
+    WHERE h.id = p_holding
+$$ LANGUAGE SQL STABLE;
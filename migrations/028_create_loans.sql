@@ -0,0 +1,47 @@
+-- Empréstimos e financiamentos com tabela de amortização (SAC ou Price).
+-- Cada pagamento é um gasto (categoria dividas) vinculado ao empréstimo; a divisão
+-- entre juros e amortização e o saldo devedor são recalculados a cada alteração.
+CREATE TABLE IF NOT EXISTS loans (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(120) NOT NULL,
+    lender VARCHAR(120),
+    principal NUMERIC(12,2) NOT NULL CHECK (principal > 0),
+    annual_rate NUMERIC(8,4) NOT NULL DEFAULT 0 CHECK (annual_rate >= 0), -- juros ao ano (%)
+    term_months INTEGER NOT NULL CHECK (term_months > 0),
+    system VARCHAR(5) NOT NULL CHECK (system IN ('sac', 'price')),
+    start_date DATE NOT NULL,              -- contratação; a parcela n vence n meses depois
+    account_id INTEGER REFERENCES accounts(id) ON DELETE SET NULL, -- conta padrão dos pagamentos
+    outstanding_balance NUMERIC(12,2) NOT NULL,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_loans_user ON loans(user_id);
+
+-- Valor e data vêm do gasto; apagar o gasto desfaz o pagamento
+CREATE TABLE IF NOT EXISTS loan_payments (
+    id SERIAL PRIMARY KEY,
+    loan_id INTEGER NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    expense_id INTEGER NOT NULL UNIQUE REFERENCES expenses(id) ON DELETE CASCADE,
+    number INTEGER NOT NULL DEFAULT 0,
+    principal NUMERIC(12,2) NOT NULL DEFAULT 0,
+    interest NUMERIC(12,2) NOT NULL DEFAULT 0,
+    balance_after NUMERIC(12,2) NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_payments_loan ON loan_payments(loan_id);
+
+-- Saldo devedor de um empréstimo ao fim de p_date
+CREATE OR REPLACE FUNCTION loan_balance(p_loan INTEGER, p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN p_date < l.start_date THEN 0 ELSE COALESCE((
+        SELECT p.balance_after
+        FROM loan_payments p
+        JOIN expenses e ON e.id = p.expense_id
+        WHERE p.loan_id = l.id AND e.date <= p_date
+        ORDER BY p.number DESC
+        LIMIT 1
+    ), l.principal) END
+    FROM loans l
+    WHERE l.id = p_loan
+$$ LANGUAGE SQL STABLE;
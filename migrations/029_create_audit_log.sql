@@ -0,0 +1,17 @@
+-- Log de auditoria: uma entrada por criação, alteração ou exclusão, com o estado
+-- do registro antes e depois (JSON), o request id e o IP de origem.
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    entity_type VARCHAR(30) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    action VARCHAR(10) NOT NULL CHECK (action IN ('create', 'update', 'delete')),
+    before JSONB,
+    after JSONB,
+    request_id VARCHAR(64),
+    ip VARCHAR(64),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_user ON audit_log(user_id, id DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(user_id, entity_type, entity_id, id DESC);
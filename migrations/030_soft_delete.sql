@@ -0,0 +1,27 @@
+-- Lixeira: gastos, rendas, metas e contas excluídos ficam marcados com deleted_at
+-- e podem ser restaurados até serem apagados de vez pela limpeza (TRASH_RETENTION_DAYS).
+-- Todas as consultas ignoram as linhas com deleted_at preenchido.
+ALTER TABLE expenses ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE incomes ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE goals ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_expenses_trash ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_incomes_trash ON incomes(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_goals_trash ON goals(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_trash ON accounts(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Pagamentos cujo gasto está na lixeira não contam no saldo devedor
+CREATE OR REPLACE FUNCTION loan_balance(p_loan INTEGER, p_date DATE)
+RETURNS NUMERIC AS $$
+    SELECT CASE WHEN p_date < l.start_date THEN 0 ELSE COALESCE((
+        SELECT p.balance_after
+        FROM loan_payments p
+        JOIN expenses e ON e.id = p.expense_id
+        WHERE p.loan_id = l.id AND e.date <= p_date AND e.deleted_at IS NULL
+        ORDER BY p.number DESC
+        LIMIT 1
+    ), l.principal) END
+    FROM loans l
+    WHERE l.id = p_loan
+$$ LANGUAGE SQL STABLE;
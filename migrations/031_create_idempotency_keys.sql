@@ -0,0 +1,16 @@
+-- Chaves de idempotência (cabeçalho Idempotency-Key) dos POSTs: guardam o hash da
+-- requisição e a resposta original, devolvida de novo quando o cliente repete a chamada.
+-- status fica NULL enquanto a primeira requisição está em processamento.
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash CHAR(64) NOT NULL,
+    status INTEGER,
+    content_type VARCHAR(255),
+    response BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created ON idempotency_keys(user_id, created_at);
@@ -0,0 +1,13 @@
+-- Versão do esquema: cada migration registra seu número aqui, e o /readyz só fica
+-- pronto quando a versão do banco alcança database.SchemaVersion. Quem chega a esta
+-- migration já aplicou as anteriores (em ordem), então elas entram todas de uma vez.
+-- Migrations novas terminam com:
+--   INSERT INTO schema_migrations (version) VALUES (<número>) ON CONFLICT DO NOTHING;
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INTEGER PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO schema_migrations (version)
+SELECT v FROM generate_series(1, 32) AS v
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,23 @@
+-- Conversão para a moeda base sem misturar moedas: quando o par não tem nenhuma
+-- cotação cadastrada a consulta falha (SQLSTATE FX001, mensagem "cotação ausente
+-- USD→BRL em 2025-01-31"), em vez de somar o valor original como se já estivesse
+-- na moeda base.
+CREATE OR REPLACE FUNCTION to_base_currency(p_user INTEGER, p_amount NUMERIC, p_from CHAR(3), p_date DATE)
+RETURNS NUMERIC AS $$
+DECLARE
+    v_base CHAR(3) := user_base_currency(p_user);
+    v_rate NUMERIC;
+BEGIN
+    IF p_amount IS NULL OR p_from = v_base THEN
+        RETURN p_amount;
+    END IF;
+    v_rate := exchange_rate(p_user, p_from, v_base, p_date);
+    IF v_rate IS NULL THEN
+        RAISE EXCEPTION 'cotação ausente %→% em %', p_from, v_base, to_char(p_date, 'YYYY-MM-DD')
+            USING ERRCODE = 'FX001';
+    END IF;
+    RETURN ROUND(p_amount * v_rate, 2);
+END
+$$ LANGUAGE plpgsql STABLE;
+
+INSERT INTO schema_migrations (version) VALUES (33) ON CONFLICT DO NOTHING;
@@ -0,0 +1,12 @@
+-- Moeda do empréstimo: principal, parcelas e saldo devedor ficam nela. Os
+-- pagamentos são lançados nessa moeda (a conta é debitada pela conversão) e o
+-- saldo devedor é convertido para a moeda base no resumo e no patrimônio.
+ALTER TABLE loans ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'BRL';
+
+-- Empréstimos existentes: moeda da conta de pagamento ou a moeda base do usuário
+-- (só na primeira aplicação, para não sobrescrever moedas escolhidas depois)
+UPDATE loans l
+SET currency = COALESCE((SELECT a.currency FROM accounts a WHERE a.id = l.account_id), user_base_currency(l.user_id))
+WHERE NOT EXISTS (SELECT 1 FROM schema_migrations WHERE version = 34);
+
+INSERT INTO schema_migrations (version) VALUES (34) ON CONFLICT DO NOTHING;